@@ -5,9 +5,14 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 
 	"folder-diff-v2/internal/compare"
+	"folder-diff-v2/internal/ignore"
 	"folder-diff-v2/internal/scanner"
 	"folder-diff-v2/internal/tui"
 )
@@ -21,8 +26,26 @@ var (
 )
 
 func main() {
-	mode := flag.String("mode", "hash", "Comparison mode: hash or filename")
-	exclude := flag.String("exclude", "", "Comma-separated list of patterns to exclude")
+	mode := flag.String("mode", "hash", "Comparison mode: hash, smart, bytes, quick or filename")
+	mtimeTolerance := flag.Duration("mtime-tolerance", 0, "Modification time difference still treated as equal in quick mode (e.g. 2s)")
+	var excludePatterns, includePatterns patternList
+	flag.Var(&excludePatterns, "exclude", "Patterns to exclude, comma-separated (repeatable, \\, for a literal comma)")
+	flag.Var(&includePatterns, "include", "Only compare files matching these patterns, comma-separated (repeatable)")
+	flag.Var(&patternFile{list: &excludePatterns}, "exclude-from", "Read exclude patterns from `FILE`, one per line (repeatable)")
+	flag.Var(&patternFile{list: &includePatterns}, "include-from", "Read include patterns from `FILE`, one per line (repeatable)")
+	symlinks := flag.String("symlinks", string(scanner.SymlinksCompareTarget), "Symbolic links: compare-target, follow or skip")
+	ignoreEOL := flag.Bool("ignore-eol", false, "Treat CRLF and LF line endings in text files as equal")
+	ignoreWhitespace := flag.Bool("ignore-whitespace", false, "Ignore all spaces and tabs within lines of text files")
+	ignoreTrailingSpace := flag.Bool("ignore-trailing-space", false, "Ignore spaces and tabs at the end of lines of text files")
+	semantic := flag.String("semantic", "", "Compare these structured formats by decoded value: json, yaml (comma-separated)")
+	compareMeta := flag.String("compare-meta", "", "Also compare these attributes: mode, owner, group, mtime (comma-separated)")
+	findRenames := flag.String("find-renames", "", "Report deleted/new text files at least this similar as renamed (e.g. 50%)")
+	respectGitignore := flag.Bool("respect-gitignore", false, "Honor .gitignore files found inside the compared trees")
+	hashAlgorithm := flag.String("hash", scanner.DefaultHashAlgorithm,
+		"Content hash algorithm: "+strings.Join(scanner.HashAlgorithms(), ", "))
+	jobs := flag.Int("jobs", runtime.GOMAXPROCS(0), "Number of files hashed at once, across both directories")
+	trashDir := flag.String("trash-dir", "", "Move entries deleted in the TUI to `DIR` instead of removing them")
+	strict := flag.Bool("strict", false, "Abort on the first unreadable file instead of marking it as an error")
 	verbose := flag.Bool("verbose", false, "Enable verbose output")
 	version := flag.Bool("version", false, "Show version information")
 	flag.Parse()
@@ -45,7 +68,19 @@ func main() {
 		fmt.Println("Examples:")
 		fmt.Println("  folder-diff /path/to/source /path/to/target")
 		fmt.Println("  folder-diff --mode=filename /path/to/source /path/to/target")
+		fmt.Println("  folder-diff --mode=smart /path/to/source /path/to/target")
+		fmt.Println("  folder-diff --mode=bytes /path/to/source /path/to/target")
+		fmt.Println("  folder-diff --mode=quick --mtime-tolerance=2s /path/to/source /path/to/target")
 		fmt.Println("  folder-diff --exclude=*.tmp,*.log /path/to/source /path/to/target")
+		fmt.Println("  folder-diff --include=*.go --include=*.proto --exclude-from=.diffignore /path/to/source /path/to/target")
+		fmt.Println("  folder-diff --respect-gitignore /path/to/repo-a /path/to/repo-b")
+		fmt.Println("  folder-diff --ignore-eol --ignore-trailing-space /path/to/windows /path/to/linux")
+		fmt.Println("  folder-diff --semantic=json,yaml /path/to/config-a /path/to/config-b")
+		fmt.Println("  folder-diff --compare-meta=mode,owner /path/to/source /path/to/target")
+		fmt.Println("  folder-diff --find-renames=50% /path/to/source /path/to/target")
+		fmt.Println("  folder-diff --symlinks=follow /path/to/source /path/to/target")
+		fmt.Println("  folder-diff --hash=md5 /path/to/source /path/to/target")
+		fmt.Println("  folder-diff --jobs=16 /path/to/source /path/to/target")
 		os.Exit(1)
 	}
 
@@ -60,53 +95,210 @@ func main() {
 		log.Fatalf("Target directory error: %v", err)
 	}
 
-	var excludePatterns []string
-	if *exclude != "" {
-		excludePatterns = strings.Split(*exclude, ",")
-	}
-
 	if *verbose {
 		fmt.Printf("folder-diff %s\n", Version)
 		fmt.Printf("Scanning directories...\n")
 		fmt.Printf("Source: %s\n", sourceDir)
 		fmt.Printf("Target: %s\n", targetDir)
 		fmt.Printf("Mode: %s\n", *mode)
+		fmt.Printf("Hash: %s\n", *hashAlgorithm)
+		fmt.Printf("Jobs: %d\n", *jobs)
 		if len(excludePatterns) > 0 {
 			fmt.Printf("Exclude patterns: %v\n", excludePatterns)
 		}
+		if len(includePatterns) > 0 {
+			fmt.Printf("Include patterns: %v\n", includePatterns)
+		}
+	}
+
+	comparisonMode, err := compare.ParseComparisonMode(*mode)
+	if err != nil {
+		log.Fatalf("Invalid --mode: %v", err)
+	}
+
+	renameThreshold, err := parsePercent(*findRenames)
+	if err != nil {
+		log.Fatalf("Invalid --find-renames: %v", err)
 	}
 
-	// Scan directories
-	s := scanner.NewScanner(excludePatterns)
-	comparator := compare.NewComparator(compare.ComparisonMode(*mode))
+	metaAttrs, err := compare.ParseMetaAttrs(*compareMeta)
+	if err != nil {
+		log.Fatalf("Invalid --compare-meta: %v", err)
+	}
 
-	sourceFiles, err := s.ScanDirectory(sourceDir)
+	semanticFormats, err := compare.ParseSemanticFormats(*semantic)
 	if err != nil {
-		log.Fatalf("Error scanning source directory: %v", err)
+		log.Fatalf("Invalid --semantic: %v", err)
+	}
+
+	if *trashDir != "" {
+		if *trashDir, err = filepath.Abs(*trashDir); err != nil {
+			log.Fatalf("Invalid --trash-dir: %v", err)
+		}
 	}
 
-	targetFiles, err := s.ScanDirectory(targetDir)
+	// Scan both directories concurrently
+	s, err := scanner.NewScanner(scanner.Options{
+		Mode:             comparisonMode,
+		ExcludePatterns:  excludePatterns,
+		IncludePatterns:  includePatterns,
+		Jobs:             *jobs,
+		HashAlgorithm:    *hashAlgorithm,
+		RespectGitignore: *respectGitignore,
+		Strict:           *strict,
+		Symlinks:         scanner.SymlinkPolicy(*symlinks),
+	})
 	if err != nil {
-		log.Fatalf("Error scanning target directory: %v", err)
+		log.Fatalf("Error: %v", err)
+	}
+	comparator := compare.NewComparator(compare.Options{
+		Mode:           comparisonMode,
+		MtimeTolerance: *mtimeTolerance,
+		Hash:           s.HashFile,
+		HashAlgorithm:  s.HashAlgorithm(),
+		Jobs:           *jobs,
+		Strict:         *strict,
+		FindRenames:    renameThreshold,
+		CompareMeta:    metaAttrs,
+		Semantic:       semanticFormats,
+		Text: compare.TextOptions{
+			IgnoreEOL:           *ignoreEOL,
+			IgnoreWhitespace:    *ignoreWhitespace,
+			IgnoreTrailingSpace: *ignoreTrailingSpace,
+		},
+	})
+
+	var (
+		wg                       sync.WaitGroup
+		sourceFiles, targetFiles []*compare.FileInfo
+		sourceErr, targetErr     error
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		sourceFiles, sourceErr = s.ScanDirectory(sourceDir)
+	}()
+	go func() {
+		defer wg.Done()
+		targetFiles, targetErr = s.ScanDirectory(targetDir)
+	}()
+	wg.Wait()
+
+	if sourceErr != nil {
+		log.Fatalf("Error scanning source directory: %v", sourceErr)
+	}
+	if targetErr != nil {
+		log.Fatalf("Error scanning target directory: %v", targetErr)
 	}
 
 	// Compare
-	result := comparator.Compare(sourceFiles, targetFiles)
+	result, err := comparator.Compare(sourceFiles, targetFiles)
+	if err != nil {
+		log.Fatalf("Error comparing directories: %v", err)
+	}
 	result.SourceRoot = sourceDir
 	result.TargetRoot = targetDir
 
 	if *verbose {
 		fmt.Printf("Found %d source files, %d target files\n", len(sourceFiles), len(targetFiles))
+		if errors := countErrors(sourceFiles) + countErrors(targetFiles); errors > 0 {
+			fmt.Printf("%d entries could not be read\n", errors)
+		}
 		fmt.Println("Starting TUI...")
 	}
 
 	// Start TUI
-	app := tui.NewApp(result, sourceDir, targetDir)
+	app := tui.NewApp(result, sourceDir, targetDir, *trashDir)
 	if err := app.Run(); err != nil {
 		log.Fatalf("Error running TUI: %v", err)
 	}
 }
 
+// patternList collects the values of a repeatable pattern flag. Each value
+// may hold several comma-separated patterns; a comma preceded by a
+// backslash stays part of the pattern, where it matches a literal comma.
+type patternList []string
+
+func (p *patternList) String() string {
+	if p == nil {
+		return ""
+	}
+	return strings.Join(*p, ",")
+}
+
+func (p *patternList) Set(value string) error {
+	*p = append(*p, splitPatterns(value)...)
+	return nil
+}
+
+// patternFile is a flag that appends the patterns read from a file to a
+// patternList. Reading happens while flags are parsed, so patterns keep
+// their command-line order, which decides precedence.
+type patternFile struct {
+	list *patternList
+}
+
+func (f *patternFile) String() string {
+	return ""
+}
+
+func (f *patternFile) Set(filename string) error {
+	patterns, err := ignore.ReadPatterns(filename)
+	if err != nil {
+		return err
+	}
+	*f.list = append(*f.list, patterns...)
+	return nil
+}
+
+// splitPatterns splits value on commas not escaped with a backslash
+func splitPatterns(value string) []string {
+	var patterns []string
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case ',':
+			patterns = append(patterns, value[start:i])
+			start = i + 1
+		}
+	}
+	patterns = append(patterns, value[start:])
+
+	kept := patterns[:0]
+	for _, pattern := range patterns {
+		if pattern != "" {
+			kept = append(kept, pattern)
+		}
+	}
+	return kept
+}
+
+// parsePercent parses a similarity threshold such as "50%" or "50". An
+// empty value yields 0, which disables the feature.
+func parsePercent(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	percent, err := strconv.Atoi(strings.TrimSuffix(value, "%"))
+	if err != nil || percent < 1 || percent > 100 {
+		return 0, fmt.Errorf("%q is not a percentage between 1%% and 100%%", value)
+	}
+	return percent, nil
+}
+
+// countErrors counts the entries that could not be read
+func countErrors(files []*compare.FileInfo) int {
+	count := 0
+	for _, file := range files {
+		if file.ErrorMessage != "" {
+			count++
+		}
+	}
+	return count
+}
+
 // validateDirectory checks if a path is a valid directory
 func validateDirectory(path string) error {
 	info, err := os.Stat(path)
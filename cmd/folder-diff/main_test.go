@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSplitPatterns(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{"", []string{}},
+		{"*.go", []string{"*.go"}},
+		{"*.go,*.proto", []string{"*.go", "*.proto"}},
+		{"*.go,,vendor/,", []string{"*.go", "vendor/"}},
+		{`a\,b,c`, []string{`a\,b`, "c"}},
+	}
+	for _, tt := range tests {
+		if got := splitPatterns(tt.value); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitPatterns(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestPatternFlags(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "patterns")
+	if err := os.WriteFile(filename, []byte("# comment\nbuild/\n!build/keep\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var excludes patternList
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flags.Var(&excludes, "exclude", "")
+	flags.Var(&patternFile{list: &excludes}, "exclude-from", "")
+
+	// Patterns from files keep their place among the command-line ones
+	err := flags.Parse([]string{"--exclude=*.log", "--exclude-from=" + filename, "--exclude=*.tmp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := patternList{"*.log", "# comment", "build/", "!build/keep", "", "*.tmp"}
+	if !reflect.DeepEqual(excludes, want) {
+		t.Errorf("patterns %q, want %q", excludes, want)
+	}
+
+	missing := filepath.Join(t.TempDir(), "missing")
+	if err := flags.Parse([]string{"--exclude-from=" + missing}); err == nil {
+		t.Error("reading a missing pattern file succeeded")
+	}
+}
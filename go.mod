@@ -2,11 +2,15 @@ module folder-diff-v2
 
 go 1.24.0
 
+require (
+	github.com/gdamore/tcell/v2 v2.13.8
+	github.com/rivo/tview v0.42.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
 require (
 	github.com/gdamore/encoding v1.0.1 // indirect
-	github.com/gdamore/tcell/v2 v2.13.8 // indirect
 	github.com/lucasb-eyer/go-colorful v1.3.0 // indirect
-	github.com/rivo/tview v0.42.0 // indirect
 	github.com/rivo/uniseg v0.4.7 // indirect
 	golang.org/x/sys v0.38.0 // indirect
 	golang.org/x/term v0.37.0 // indirect
@@ -0,0 +1,61 @@
+package compare
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// byteChunkSize is how much of each file is held in memory at a time
+const byteChunkSize = 64 * 1024
+
+// firstDifference streams two files side by side and returns the offset of
+// the first differing byte, or -1 if their contents are equal. When one
+// file is a prefix of the other, the difference starts where the shorter
+// one ends.
+func firstDifference(sourcePath, targetPath string) (int64, error) {
+	source, err := os.Open(sourcePath)
+	if err != nil {
+		return 0, err
+	}
+	defer source.Close()
+
+	target, err := os.Open(targetPath)
+	if err != nil {
+		return 0, err
+	}
+	defer target.Close()
+
+	sourceBuf := make([]byte, byteChunkSize)
+	targetBuf := make([]byte, byteChunkSize)
+	var offset int64
+
+	for {
+		sourceN, sourceErr := io.ReadFull(source, sourceBuf)
+		if sourceErr != nil && sourceErr != io.EOF && sourceErr != io.ErrUnexpectedEOF {
+			return 0, sourceErr
+		}
+		targetN, targetErr := io.ReadFull(target, targetBuf)
+		if targetErr != nil && targetErr != io.EOF && targetErr != io.ErrUnexpectedEOF {
+			return 0, targetErr
+		}
+
+		n := min(sourceN, targetN)
+		if !bytes.Equal(sourceBuf[:n], targetBuf[:n]) {
+			for i := 0; i < n; i++ {
+				if sourceBuf[i] != targetBuf[i] {
+					return offset + int64(i), nil
+				}
+			}
+		}
+		if sourceN != targetN {
+			return offset + int64(n), nil
+		}
+
+		// A short read means both files ended at the same offset
+		if sourceN < byteChunkSize {
+			return -1, nil
+		}
+		offset += int64(n)
+	}
+}
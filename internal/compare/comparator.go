@@ -1,23 +1,106 @@
 package compare
 
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// HashFunc returns the content hash of the file at path
+type HashFunc func(path string) (string, error)
+
+// Options configures a Comparator
+type Options struct {
+	Mode ComparisonMode
+
+	// MtimeTolerance is the largest modification time difference that
+	// QuickMode still treats as equal. Copies to FAT or SMB shares round
+	// timestamps, so an exact match would report them all as modified.
+	MtimeTolerance time.Duration
+
+	// Hash computes content hashes on demand. SmartMode requires it to
+	// hash files whose sizes match; everything else is never read.
+	Hash HashFunc
+
+	// HashAlgorithm names the algorithm behind Hash. Hashes already
+	// present on the compared files must have been produced with it.
+	HashAlgorithm string
+
+	// Jobs is the number of concurrent workers used for on-demand
+	// hashing. A value below 1 defaults to GOMAXPROCS.
+	Jobs int
+
+	// Text selects differences ignored in text files. Files found to
+	// differ are re-read and normalized, and reported Identical with
+	// RawDiffers set when only ignored differences remain.
+	Text TextOptions
+
+	// Semantic lists structured data formats compared by decoded value
+	// rather than by bytes, recognized by file extension
+	Semantic []SemanticFormat
+
+	// CompareMeta lists attributes compared in addition to content.
+	// Entries whose content matches but attributes differ are reported
+	// as MetaChanged.
+	CompareMeta []MetaAttr
+
+	// FindRenames enables similarity-based rename detection: deleted and
+	// new text files whose content is at least this percent similar are
+	// reported as Renamed. Zero disables it.
+	FindRenames int
+
+	// Strict makes Compare fail when a file cannot be read on demand.
+	// By default both sides of such a pair get Error status instead.
+	Strict bool
+}
+
+// filePair is a file present on both sides whose status is still pending
+type filePair struct {
+	source, target *FileInfo
+}
+
 type Comparator struct {
-	mode ComparisonMode
+	opts Options
 }
 
-func NewComparator(mode ComparisonMode) *Comparator {
-	return &Comparator{mode: mode}
+func NewComparator(opts Options) *Comparator {
+	if opts.Jobs < 1 {
+		opts.Jobs = runtime.GOMAXPROCS(0)
+	}
+	return &Comparator{opts: opts}
 }
 
-func (c *Comparator) Compare(source, target []*FileInfo) *ComparisonResult {
+// Compare matches source and target entries by relative path and sets the
+// Status of each. It refuses to compare hashes produced by different
+// algorithms and otherwise fails only if reading file contents on demand
+// fails.
+func (c *Comparator) Compare(source, target []*FileInfo) (*ComparisonResult, error) {
+	if c.opts.Mode == SmartMode && c.opts.Hash == nil {
+		return nil, errors.New("smart mode requires a hash function")
+	}
+
+	algorithm, err := c.hashAlgorithm(source, target)
+	if err != nil {
+		return nil, err
+	}
+
 	result := &ComparisonResult{
-		SourceFiles: source,
-		TargetFiles: target,
-		Mode:        c.mode,
+		SourceFiles:    source,
+		TargetFiles:    target,
+		Mode:           c.opts.Mode,
+		HashAlgorithm:  algorithm,
+		CompareMeta:    c.opts.CompareMeta,
+		MtimeTolerance: c.opts.MtimeTolerance,
 	}
 
 	sourceMap := make(map[string]*FileInfo)
 	targetMap := make(map[string]*FileInfo)
 
+	var pending, matched []filePair
+
 	for _, file := range source {
 		sourceMap[file.RelPath] = file
 	}
@@ -26,35 +109,388 @@ func (c *Comparator) Compare(source, target []*FileInfo) *ComparisonResult {
 		targetMap[file.RelPath] = file
 
 		if sourceFile, exists := sourceMap[file.RelPath]; exists {
-			if file.IsDir {
-				file.Status = Identical
-				sourceFile.Status = Identical
+			matched = append(matched, filePair{source: sourceFile, target: file})
+
+			// A side that could not be read leaves nothing to compare
+			if file.Status == Error || sourceFile.Status == Error {
+				markError(sourceFile, file)
 				continue
 			}
 
-			if c.mode == HashMode {
-				if file.Hash == sourceFile.Hash {
-					file.Status = Identical
-					sourceFile.Status = Identical
-				} else {
-					file.Status = Modified
-					sourceFile.Status = Modified
+			// A file replaced by a directory (or link) has no content
+			// to compare; both subtrees are reported as they are
+			if file.EntryType() != sourceFile.EntryType() {
+				file.Status = TypeChanged
+				sourceFile.Status = TypeChanged
+				continue
+			}
+
+			// Unfollowed links are compared by where they point
+			if file.IsSymlink {
+				status := Identical
+				if file.LinkTarget != sourceFile.LinkTarget {
+					status = Modified
 				}
-			} else {
+				file.Status = status
+				sourceFile.Status = status
+				continue
+			}
+
+			if file.IsDir {
 				file.Status = Identical
 				sourceFile.Status = Identical
+				continue
 			}
-		} else {
+
+			if c.opts.Mode == BytesMode ||
+				(c.opts.Mode == SmartMode && file.Size == sourceFile.Size) {
+				pending = append(pending, filePair{source: sourceFile, target: file})
+				continue
+			}
+
+			status := c.compareFiles(sourceFile, file)
+			file.Status = status
+			sourceFile.Status = status
+		} else if file.Status != Error {
 			file.Status = New
 		}
 	}
 
 	// Mark deleted files
 	for _, file := range source {
-		if _, exists := targetMap[file.RelPath]; !exists {
+		if _, exists := targetMap[file.RelPath]; !exists && file.Status != Error {
 			file.Status = Deleted
 		}
 	}
 
-	return result
+	// Files deleted in one place and added in another with the same
+	// content were moved
+	if c.opts.Mode == HashMode {
+		detectMoves(source, target)
+	}
+
+	// Edited files that also moved are only found by content similarity
+	if c.opts.FindRenames > 0 {
+		if err := c.detectRenames(source, target, c.opts.FindRenames); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.resolvePending(pending, result); err != nil {
+		return nil, err
+	}
+
+	// Files that differ byte-wise may still hold the same data
+	var structured, text []filePair
+	for _, pair := range matched {
+		if pair.source.Status != Modified || pair.source.IsDir || pair.source.IsSymlink || !c.comparesContent() {
+			continue
+		}
+		if _, ok := c.semanticFormat(pair.source.Path); ok {
+			structured = append(structured, pair)
+		} else if c.opts.Text.enabled() {
+			text = append(text, pair)
+		}
+	}
+	if err := c.forEachPair(structured, c.compareSemantic); err != nil {
+		return nil, err
+	}
+	if err := c.forEachPair(text, c.compareText); err != nil {
+		return nil, err
+	}
+
+	// Attributes only matter once content is known to be the same
+	if len(c.opts.CompareMeta) > 0 {
+		for _, pair := range matched {
+			if pair.source.Status == Identical {
+				c.compareMeta(pair)
+			}
+		}
+	}
+
+	rollUpDirs(sourceMap, targetMap)
+
+	return result, nil
+}
+
+// resolvePending decides the status of the pairs that need their contents
+// read: byte by byte in BytesMode, by hash otherwise
+func (c *Comparator) resolvePending(pending []filePair, result *ComparisonResult) error {
+	if c.opts.Mode == BytesMode {
+		return c.forEachPair(pending, c.compareBytes)
+	}
+
+	// Only files that could not be told apart by size are read
+	if err := c.forEachPair(pending, c.hashPair); err != nil {
+		return err
+	}
+	for _, pair := range pending {
+		if pair.source.Status == Error || pair.target.Status == Error {
+			continue
+		}
+		status := c.compareFiles(pair.source, pair.target)
+		pair.source.Status = status
+		pair.target.Status = status
+	}
+	if len(pending) > 0 {
+		result.HashAlgorithm = c.opts.HashAlgorithm
+	}
+	return nil
+}
+
+// rollUpDirs marks directories present on both sides as ContainsChanges
+// when any entry below them, on either side, is not Identical
+func rollUpDirs(sourceMap, targetMap map[string]*FileInfo) {
+	for _, files := range []map[string]*FileInfo{sourceMap, targetMap} {
+		for relPath, file := range files {
+			if file.Status == Identical || file.Status == ContainsChanges || relPath == "." {
+				continue
+			}
+			for dir := filepath.Dir(relPath); ; dir = filepath.Dir(dir) {
+				sourceDir, inSource := sourceMap[dir]
+				targetDir, inTarget := targetMap[dir]
+				if inSource && sourceDir.Status == ContainsChanges {
+					// Marked while rolling up an earlier entry, along
+					// with its own ancestors
+					break
+				}
+				if inSource && inTarget && sourceDir.Status == Identical && targetDir.Status == Identical {
+					sourceDir.Status = ContainsChanges
+					targetDir.Status = ContainsChanges
+				}
+				if dir == "." {
+					break
+				}
+			}
+		}
+	}
+}
+
+// hashAlgorithm returns the single algorithm behind the hashes already
+// present on both sides, or an error if hashes from different algorithms
+// would end up being compared.
+func (c *Comparator) hashAlgorithm(source, target []*FileInfo) (string, error) {
+	sourceAlgorithm, err := algorithmOf(source)
+	if err != nil {
+		return "", fmt.Errorf("source: %w", err)
+	}
+	targetAlgorithm, err := algorithmOf(target)
+	if err != nil {
+		return "", fmt.Errorf("target: %w", err)
+	}
+
+	algorithm := sourceAlgorithm
+	for _, other := range []string{targetAlgorithm, c.opts.HashAlgorithm} {
+		if other == "" {
+			continue
+		}
+		if algorithm == "" {
+			algorithm = other
+		} else if other != algorithm {
+			return "", fmt.Errorf("cannot compare %s hashes with %s hashes", algorithm, other)
+		}
+	}
+
+	if sourceAlgorithm == "" && targetAlgorithm == "" {
+		return "", nil
+	}
+	return algorithm, nil
+}
+
+// algorithmOf returns the algorithm shared by all hashed files
+func algorithmOf(files []*FileInfo) (string, error) {
+	algorithm := ""
+	for _, file := range files {
+		if file.Hash == "" {
+			continue
+		}
+		if algorithm == "" {
+			algorithm = file.HashAlgorithm
+		} else if file.HashAlgorithm != algorithm {
+			return "", fmt.Errorf("%s was hashed with %s, other files with %s",
+				file.RelPath, file.HashAlgorithm, algorithm)
+		}
+	}
+	return algorithm, nil
+}
+
+// forEachPair runs fn over pairs using a bounded pool of workers and
+// returns the first error, after which no further pairs are started
+func (c *Comparator) forEachPair(pairs []filePair, fn func(filePair) error) error {
+	if len(pairs) == 0 {
+		return nil
+	}
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	work := make(chan filePair)
+	done := make(chan struct{})
+
+	for i := 0; i < c.opts.Jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for pair := range work {
+				if err := fn(pair); err != nil {
+					once.Do(func() {
+						firstErr = err
+						close(done)
+					})
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, pair := range pairs {
+		select {
+		case <-done:
+			break feed
+		case work <- pair:
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	return firstErr
+}
+
+// fail handles an error reading one side of a pair: it is returned in
+// strict mode and recorded on both sides otherwise
+func (c *Comparator) fail(pair filePair, file *FileInfo, err error) error {
+	if c.opts.Strict {
+		return err
+	}
+	file.SetError(err)
+	markError(pair.source, pair.target)
+	return nil
+}
+
+// markError gives both sides Error status. Only the side that failed
+// carries an ErrorMessage.
+func markError(source, target *FileInfo) {
+	source.Status = Error
+	target.Status = Error
+}
+
+// hashPair fills in the missing hashes of both sides of a pair
+func (c *Comparator) hashPair(pair filePair) error {
+	for _, file := range []*FileInfo{pair.source, pair.target} {
+		if file.Hash != "" {
+			continue
+		}
+		sum, err := c.opts.Hash(file.Path)
+		if err != nil {
+			return c.fail(pair, file, err)
+		}
+		file.Hash = sum
+		file.HashAlgorithm = c.opts.HashAlgorithm
+	}
+	return nil
+}
+
+// compareBytes streams both sides of a pair and records where they first
+// differ
+func (c *Comparator) compareBytes(pair filePair) error {
+	offset, err := firstDifference(pair.source.Path, pair.target.Path)
+	if err != nil {
+		if c.opts.Strict {
+			return err
+		}
+		// The error names the path that failed; both sides carry it
+		pair.source.SetError(err)
+		pair.target.SetError(err)
+		return nil
+	}
+
+	status := Identical
+	if offset >= 0 {
+		status = Modified
+		pair.source.DiffOffset = offset
+		pair.target.DiffOffset = offset
+	}
+	pair.source.Status = status
+	pair.target.Status = status
+	return nil
+}
+
+// comparesContent reports whether pairs marked Modified are known to
+// differ in their bytes: hash and byte comparisons read them, and smart
+// mode either does or found their sizes to differ. QuickMode never reads
+// files, so a Modified pair may only differ in modification time, and
+// FilenameMode does not compare at all.
+func (c *Comparator) comparesContent() bool {
+	switch c.opts.Mode {
+	case HashMode, SmartMode, BytesMode:
+		return true
+	default:
+		return false
+	}
+}
+
+// compareText re-compares a modified pair as normalized text
+func (c *Comparator) compareText(pair filePair) error {
+	equal, err := c.opts.Text.equalText(pair.source.Path, pair.target.Path)
+	if err != nil {
+		if c.opts.Strict {
+			return err
+		}
+		// The raw comparison already found a difference; keep it
+		return nil
+	}
+
+	if equal {
+		pair.source.Status = Identical
+		pair.source.RawDiffers = true
+		pair.target.Status = Identical
+		pair.target.RawDiffers = true
+	}
+	return nil
+}
+
+// compareFiles decides the status of a file present on both sides
+func (c *Comparator) compareFiles(source, target *FileInfo) FileStatus {
+	switch c.opts.Mode {
+	case HashMode:
+		return compareHashes(source, target)
+	case SmartMode:
+		if source.Size != target.Size {
+			return Modified
+		}
+		return compareHashes(source, target)
+	case QuickMode:
+		if source.Size == target.Size && c.sameModTime(source.ModTime, target.ModTime) {
+			return Identical
+		}
+		return Modified
+	default:
+		return Identical
+	}
+}
+
+// compareHashes compares content hashes. Entries scanned without content
+// hashes can only be matched by name.
+func compareHashes(source, target *FileInfo) FileStatus {
+	if source.Hash == "" || target.Hash == "" || source.Hash == target.Hash {
+		return Identical
+	}
+	return Modified
+}
+
+// sameModTime reports whether two modification times are within the
+// configured tolerance of each other
+func (c *Comparator) sameModTime(a, b time.Time) bool {
+	return withinTolerance(a, b, c.opts.MtimeTolerance)
+}
+
+// withinTolerance reports whether two times are at most tolerance apart
+func withinTolerance(a, b time.Time, tolerance time.Duration) bool {
+	diff := a.Sub(b)
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= tolerance
 }
@@ -0,0 +1,188 @@
+package compare
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// baseTime is the modification time given to test files unless a case
+// shifts it
+var baseTime = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// writeFile creates the file at path with content and modification time,
+// along with its parent directories
+func writeFile(t *testing.T, path, content string, modTime time.Time) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// statFile describes the file at root/relPath the way a scan would
+func statFile(t *testing.T, root, relPath string) *FileInfo {
+	t.Helper()
+	path := filepath.Join(root, relPath)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &FileInfo{
+		Path:    path,
+		RelPath: relPath,
+		Name:    info.Name(),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		Mode:    info.Mode(),
+		IsDir:   info.IsDir(),
+	}
+}
+
+// sha256File is a HashFunc for tests
+func sha256File(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// comparePair writes one file on each side and compares them with opts,
+// hashing both up front in HashMode as the scanner would
+func comparePair(t *testing.T, opts Options, name, source, target string, targetTime time.Time) (*FileInfo, *FileInfo) {
+	t.Helper()
+	sourceRoot, targetRoot := t.TempDir(), t.TempDir()
+	writeFile(t, filepath.Join(sourceRoot, name), source, baseTime)
+	writeFile(t, filepath.Join(targetRoot, name), target, targetTime)
+
+	sourceFile := statFile(t, sourceRoot, name)
+	targetFile := statFile(t, targetRoot, name)
+	if opts.Mode == HashMode {
+		for _, file := range []*FileInfo{sourceFile, targetFile} {
+			sum, err := sha256File(file.Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			file.Hash = sum
+			file.HashAlgorithm = "sha256"
+		}
+	}
+	if opts.Hash != nil && opts.HashAlgorithm == "" {
+		opts.HashAlgorithm = "sha256"
+	}
+
+	if _, err := NewComparator(opts).Compare([]*FileInfo{sourceFile}, []*FileInfo{targetFile}); err != nil {
+		t.Fatal(err)
+	}
+	if sourceFile.Status != targetFile.Status {
+		t.Fatalf("source status %s differs from target status %s", sourceFile.Status, targetFile.Status)
+	}
+	return sourceFile, targetFile
+}
+
+func TestParseComparisonMode(t *testing.T) {
+	for _, mode := range ComparisonModes {
+		if got, err := ParseComparisonMode(string(mode)); err != nil || got != mode {
+			t.Errorf("ParseComparisonMode(%q) = %q, %v", mode, got, err)
+		}
+	}
+
+	_, err := ParseComparisonMode("fast")
+	if err == nil {
+		t.Fatal("ParseComparisonMode(\"fast\") succeeded")
+	}
+	for _, mode := range ComparisonModes {
+		if !strings.Contains(err.Error(), string(mode)) {
+			t.Errorf("error %q does not list mode %q", err, mode)
+		}
+	}
+}
+
+func TestCompareModes(t *testing.T) {
+	later := baseTime.Add(time.Hour)
+	tests := []struct {
+		name           string
+		opts           Options
+		source, target string
+		targetTime     time.Time
+		want           FileStatus
+		wantOffset     int64
+	}{
+		{"hash ignores mtime", Options{Mode: HashMode}, "same", "same", later, Identical, 0},
+		{"hash same size content differs", Options{Mode: HashMode}, "abcd", "abce", baseTime, Modified, 0},
+		{"quick same size and mtime", Options{Mode: QuickMode}, "abcd", "wxyz", baseTime, Identical, 0},
+		{"quick mtime differs", Options{Mode: QuickMode}, "same", "same", later, Modified, 0},
+		{"quick size differs", Options{Mode: QuickMode}, "abc", "abcd", baseTime, Modified, 0},
+		{"quick mtime within tolerance", Options{Mode: QuickMode, MtimeTolerance: 2 * time.Second}, "same", "same", baseTime.Add(time.Second), Identical, 0},
+		{"quick mtime beyond tolerance", Options{Mode: QuickMode, MtimeTolerance: 2 * time.Second}, "same", "same", baseTime.Add(3 * time.Second), Modified, 0},
+		{"smart size differs", Options{Mode: SmartMode, Hash: sha256File}, "abc", "abcd", baseTime, Modified, 0},
+		{"smart same size content differs", Options{Mode: SmartMode, Hash: sha256File}, "abcd", "abce", baseTime, Modified, 0},
+		{"smart same content", Options{Mode: SmartMode, Hash: sha256File}, "same", "same", later, Identical, 0},
+		{"bytes first difference", Options{Mode: BytesMode}, "abcdef", "abcxef", baseTime, Modified, 3},
+		{"bytes one file longer", Options{Mode: BytesMode}, "abc", "abcd", baseTime, Modified, 3},
+		{"bytes same content", Options{Mode: BytesMode}, "same", "same", later, Identical, 0},
+		{"filename ignores content", Options{Mode: FilenameMode}, "abc", "xyz!", later, Identical, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, _ := comparePair(t, tt.opts, "file.txt", tt.source, tt.target, tt.targetTime)
+			if source.Status != tt.want {
+				t.Errorf("status %s, want %s", source.Status, tt.want)
+			}
+			if source.DiffOffset != tt.wantOffset {
+				t.Errorf("diff offset %d, want %d", source.DiffOffset, tt.wantOffset)
+			}
+		})
+	}
+}
+
+func TestCompareSmartHashesOnlySameSize(t *testing.T) {
+	var hashed []string
+	hash := func(path string) (string, error) {
+		hashed = append(hashed, filepath.Base(filepath.Dir(path)))
+		return sha256File(path)
+	}
+
+	sourceRoot, targetRoot := t.TempDir(), t.TempDir()
+	for _, root := range []string{sourceRoot, targetRoot} {
+		writeFile(t, filepath.Join(root, "same.txt"), "same", baseTime)
+	}
+	writeFile(t, filepath.Join(sourceRoot, "grown.txt"), "abc", baseTime)
+	writeFile(t, filepath.Join(targetRoot, "grown.txt"), "abcd", baseTime)
+
+	source := []*FileInfo{statFile(t, sourceRoot, "same.txt"), statFile(t, sourceRoot, "grown.txt")}
+	target := []*FileInfo{statFile(t, targetRoot, "same.txt"), statFile(t, targetRoot, "grown.txt")}
+	c := NewComparator(Options{Mode: SmartMode, Hash: hash, HashAlgorithm: "sha256", Jobs: 1})
+	result, err := c.Compare(source, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(hashed) != 2 {
+		t.Errorf("hashed %d files, want only the 2 of the same size", len(hashed))
+	}
+	if source[0].Status != Identical || source[1].Status != Modified {
+		t.Errorf("statuses %s, %s; want %s, %s", source[0].Status, source[1].Status, Identical, Modified)
+	}
+	if result.HashAlgorithm != "sha256" {
+		t.Errorf("result hash algorithm %q, want sha256", result.HashAlgorithm)
+	}
+}
+
+func TestCompareSmartRequiresHash(t *testing.T) {
+	if _, err := NewComparator(Options{Mode: SmartMode}).Compare(nil, nil); err == nil {
+		t.Error("smart mode without a hash function succeeded")
+	}
+}
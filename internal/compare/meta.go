@@ -0,0 +1,90 @@
+package compare
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// MetaAttr names a file attribute compared in addition to content
+type MetaAttr string
+
+const (
+	MetaMode  MetaAttr = "mode"  // Permission, setuid, setgid and sticky bits
+	MetaOwner MetaAttr = "owner" // Owning user ID
+	MetaGroup MetaAttr = "group" // Owning group ID
+	MetaMtime MetaAttr = "mtime" // Modification time, within Options.MtimeTolerance
+)
+
+// MetaAttrs lists the attributes accepted by ParseMetaAttrs
+var MetaAttrs = []MetaAttr{MetaMode, MetaOwner, MetaGroup, MetaMtime}
+
+// modeBits are the mode bits compared by MetaMode
+const modeBits = os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+
+// ParseMetaAttrs parses a comma-separated attribute list such as
+// "mode,owner". An empty string yields no attributes.
+func ParseMetaAttrs(value string) ([]MetaAttr, error) {
+	var attrs []MetaAttr
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		attr, err := parseMetaAttr(name)
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, attr)
+	}
+	return attrs, nil
+}
+
+func parseMetaAttr(name string) (MetaAttr, error) {
+	names := make([]string, len(MetaAttrs))
+	for i, attr := range MetaAttrs {
+		if string(attr) == name {
+			return attr, nil
+		}
+		names[i] = string(attr)
+	}
+	return "", fmt.Errorf("unknown attribute %q (supported: %s)", name, strings.Join(names, ", "))
+}
+
+// compareMeta marks a pair with identical content MetaChanged when any of
+// the configured attributes differ, recording which ones on both sides
+func (c *Comparator) compareMeta(pair filePair) {
+	source, target := pair.source, pair.target
+
+	diff := MetaDiff(source, target, c.opts.CompareMeta, c.opts.MtimeTolerance)
+	if len(diff) > 0 {
+		source.Status = MetaChanged
+		source.MetaDiff = diff
+		target.Status = MetaChanged
+		target.MetaDiff = diff
+	}
+}
+
+// MetaDiff returns the attributes among attrs in which source and target
+// differ. Modification times within tolerance of each other are equal.
+func MetaDiff(source, target *FileInfo, attrs []MetaAttr, tolerance time.Duration) []MetaAttr {
+	var diff []MetaAttr
+	for _, attr := range attrs {
+		var differs bool
+		switch attr {
+		case MetaMode:
+			differs = source.Mode&modeBits != target.Mode&modeBits
+		case MetaOwner:
+			differs = source.UID != target.UID
+		case MetaGroup:
+			differs = source.GID != target.GID
+		case MetaMtime:
+			differs = !withinTolerance(source.ModTime, target.ModTime, tolerance)
+		}
+		if differs {
+			diff = append(diff, attr)
+		}
+	}
+	return diff
+}
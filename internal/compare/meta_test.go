@@ -0,0 +1,104 @@
+package compare
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseMetaAttrs(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []MetaAttr
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"mode", []MetaAttr{MetaMode}, false},
+		{"mode, owner,group,mtime", []MetaAttr{MetaMode, MetaOwner, MetaGroup, MetaMtime}, false},
+		{"mode,,", []MetaAttr{MetaMode}, false},
+		{"mode,size", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMetaAttrs(tt.value)
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseMetaAttrs(%q) = %v, %v; want %v, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestMetaDiff(t *testing.T) {
+	all := MetaAttrs
+	base := FileInfo{Mode: 0o644, UID: 1000, GID: 100, ModTime: baseTime}
+	tests := []struct {
+		name      string
+		change    func(f *FileInfo)
+		attrs     []MetaAttr
+		tolerance time.Duration
+		want      []MetaAttr
+	}{
+		{"nothing differs", func(f *FileInfo) {}, all, 0, nil},
+		{"permissions", func(f *FileInfo) { f.Mode = 0o600 }, all, 0, []MetaAttr{MetaMode}},
+		{"setuid bit", func(f *FileInfo) { f.Mode |= os.ModeSetuid }, all, 0, []MetaAttr{MetaMode}},
+		{"sticky bit", func(f *FileInfo) { f.Mode |= os.ModeSticky }, all, 0, []MetaAttr{MetaMode}},
+		{"file type is not a mode difference", func(f *FileInfo) { f.Mode |= os.ModeDir }, all, 0, nil},
+		{"owner and group", func(f *FileInfo) { f.UID, f.GID = 0, 0 }, all, 0, []MetaAttr{MetaOwner, MetaGroup}},
+		{"owner not compared", func(f *FileInfo) { f.UID = 0 }, []MetaAttr{MetaMode, MetaGroup}, 0, nil},
+		{"mtime", func(f *FileInfo) { f.ModTime = baseTime.Add(time.Second) }, all, 0, []MetaAttr{MetaMtime}},
+		{"mtime within tolerance", func(f *FileInfo) { f.ModTime = baseTime.Add(-time.Second) }, all, 2 * time.Second, nil},
+		{"everything", func(f *FileInfo) { *f = FileInfo{Mode: 0o755} }, all, 0, all},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, target := base, base
+			tt.change(&target)
+			if got := MetaDiff(&source, &target, tt.attrs, tt.tolerance); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MetaDiff = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareMeta(t *testing.T) {
+	tests := []struct {
+		name           string
+		source, target string
+		targetMode     os.FileMode
+		attrs          []MetaAttr
+		want           FileStatus
+		wantDiff       []MetaAttr
+	}{
+		{"mode differs", "same", "same", 0o600, []MetaAttr{MetaMode}, MetaChanged, []MetaAttr{MetaMode}},
+		{"mode not compared", "same", "same", 0o600, []MetaAttr{MetaOwner}, Identical, nil},
+		{"nothing differs", "same", "same", 0o644, []MetaAttr{MetaMode, MetaOwner, MetaGroup, MetaMtime}, Identical, nil},
+		{"content differences win", "abcd", "abce", 0o600, []MetaAttr{MetaMode}, Modified, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceRoot, targetRoot := t.TempDir(), t.TempDir()
+			writeFile(t, filepath.Join(sourceRoot, "file"), tt.source, baseTime)
+			writeFile(t, filepath.Join(targetRoot, "file"), tt.target, baseTime)
+			if err := os.Chmod(filepath.Join(targetRoot, "file"), tt.targetMode); err != nil {
+				t.Fatal(err)
+			}
+			source, target := statFile(t, sourceRoot, "file"), statFile(t, targetRoot, "file")
+
+			opts := Options{Mode: SmartMode, Hash: sha256File, HashAlgorithm: "sha256", CompareMeta: tt.attrs}
+			result, err := NewComparator(opts).Compare([]*FileInfo{source}, []*FileInfo{target})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(result.CompareMeta, tt.attrs) {
+				t.Errorf("result compares %v, want %v", result.CompareMeta, tt.attrs)
+			}
+			for _, file := range []*FileInfo{source, target} {
+				if file.Status != tt.want || !reflect.DeepEqual(file.MetaDiff, tt.wantDiff) {
+					t.Errorf("%s: status %s, meta diff %v; want %s, %v", file.Path, file.Status, file.MetaDiff, tt.want, tt.wantDiff)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package compare
+
+import "path/filepath"
+
+// detectMoves pairs files that only exist in the source with files that
+// only exist in the target when their content hashes are equal, and marks
+// both ends Moved. Among several candidates with the same content, one
+// with the same base name is preferred, then the first in path order, so
+// the pairing is deterministic.
+func detectMoves(source, target []*FileInfo) {
+	added := make(map[string][]*FileInfo)
+	for _, file := range target {
+		if isMoveCandidate(file, New) {
+			added[file.Hash] = append(added[file.Hash], file)
+		}
+	}
+	if len(added) == 0 {
+		return
+	}
+
+	for _, file := range source {
+		if !isMoveCandidate(file, Deleted) {
+			continue
+		}
+
+		candidates := added[file.Hash]
+		if len(candidates) == 0 {
+			continue
+		}
+
+		best := 0
+		for i, candidate := range candidates {
+			if filepath.Base(candidate.RelPath) == filepath.Base(file.RelPath) {
+				best = i
+				break
+			}
+		}
+		match := candidates[best]
+		added[file.Hash] = append(candidates[:best:best], candidates[best+1:]...)
+
+		file.Status = Moved
+		file.Counterpart = match.RelPath
+		match.Status = Moved
+		match.Counterpart = file.RelPath
+	}
+}
+
+// isMoveCandidate reports whether file is a hashed regular file with the
+// given one-sided status
+func isMoveCandidate(file *FileInfo, status FileStatus) bool {
+	return file.Status == status && !file.IsDir && !file.IsSymlink && file.Hash != ""
+}
@@ -0,0 +1,168 @@
+package compare
+
+import (
+	"bytes"
+	"hash/fnv"
+	"os"
+	"sort"
+)
+
+const (
+	// maxRenameFileSize bounds the files considered for similarity-based
+	// rename detection, since each candidate is read whole to hash its lines
+	maxRenameFileSize = 4 << 20
+
+	// maxRenamePairs bounds the number of deleted/new pairs scored, like
+	// git's rename limit, so huge one-sided trees do not stall the run
+	maxRenamePairs = 1000 * 1000
+)
+
+// renameCandidate is a one-sided text file with its line signature
+type renameCandidate struct {
+	file  *FileInfo
+	size  int64
+	lines map[uint64]int64 // Bytes of content per distinct line, keyed by line hash
+}
+
+// renamePair is a scored deleted/new pair
+type renamePair struct {
+	source, target *renameCandidate
+	score          int
+}
+
+// detectRenames pairs the deleted and new text files left after exact
+// move detection when their content similarity reaches threshold percent,
+// marking both ends Renamed. Similarity is the share of bytes in common
+// lines relative to the larger file, as in git's rename detection. The
+// best scoring pairs are taken first.
+func (c *Comparator) detectRenames(source, target []*FileInfo, threshold int) error {
+	var deleted, added []*FileInfo
+	for _, file := range source {
+		if isRenameCandidate(file, Deleted) {
+			deleted = append(deleted, file)
+		}
+	}
+	for _, file := range target {
+		if isRenameCandidate(file, New) {
+			added = append(added, file)
+		}
+	}
+	if len(deleted) == 0 || len(added) == 0 || len(deleted)*len(added) > maxRenamePairs {
+		return nil
+	}
+
+	deletedCandidates, err := c.loadRenameCandidates(deleted)
+	if err != nil {
+		return err
+	}
+	addedCandidates, err := c.loadRenameCandidates(added)
+	if err != nil {
+		return err
+	}
+
+	var pairs []renamePair
+	for _, from := range deletedCandidates {
+		for _, to := range addedCandidates {
+			// Even identical lines cannot make up for the size gap
+			if sizeSimilarity(from.size, to.size) < threshold {
+				continue
+			}
+			if score := similarity(from, to); score >= threshold {
+				pairs = append(pairs, renamePair{source: from, target: to, score: score})
+			}
+		}
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].score > pairs[j].score
+	})
+
+	for _, pair := range pairs {
+		from, to := pair.source.file, pair.target.file
+		if from.Status != Deleted || to.Status != New {
+			continue
+		}
+		from.Status = Renamed
+		from.Counterpart = to.RelPath
+		from.Similarity = pair.score
+		to.Status = Renamed
+		to.Counterpart = from.RelPath
+		to.Similarity = pair.score
+	}
+	return nil
+}
+
+// isRenameCandidate reports whether file is a small enough regular file
+// with the given one-sided status
+func isRenameCandidate(file *FileInfo, status FileStatus) bool {
+	return file.Status == status && !file.IsDir && !file.IsSymlink && file.Size <= maxRenameFileSize
+}
+
+// loadRenameCandidates reads the files and computes their line signatures,
+// leaving out binary files. Unreadable files are skipped unless strict.
+func (c *Comparator) loadRenameCandidates(files []*FileInfo) ([]*renameCandidate, error) {
+	var candidates []*renameCandidate
+	for _, file := range files {
+		data, err := os.ReadFile(file.Path)
+		if err != nil {
+			if c.opts.Strict {
+				return nil, err
+			}
+			continue
+		}
+		if IsBinary(data) {
+			continue
+		}
+		candidates = append(candidates, &renameCandidate{
+			file:  file,
+			size:  int64(len(data)),
+			lines: lineSignature(data),
+		})
+	}
+	return candidates, nil
+}
+
+// lineSignature counts the bytes of each distinct line, newline included.
+// Lines are keyed by their FNV-1a hash so that only the signature, not the
+// content, stays in memory while candidates are paired.
+func lineSignature(data []byte) map[uint64]int64 {
+	lines := make(map[uint64]int64)
+	h := fnv.New64a()
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, '\n') + 1
+		if end == 0 {
+			end = len(data)
+		}
+		h.Reset()
+		h.Write(data[:end])
+		lines[h.Sum64()] += int64(end)
+		data = data[end:]
+	}
+	return lines
+}
+
+// similarity returns the percentage of content two files have in common
+func similarity(a, b *renameCandidate) int {
+	larger := max(a.size, b.size)
+	if larger == 0 {
+		return 100
+	}
+
+	var common int64
+	for line, bytesA := range a.lines {
+		if bytesB, ok := b.lines[line]; ok {
+			common += min(bytesA, bytesB)
+		}
+	}
+	return int(common * 100 / larger)
+}
+
+// sizeSimilarity is the highest similarity two files of these sizes can
+// reach
+func sizeSimilarity(a, b int64) int {
+	larger := max(a, b)
+	if larger == 0 {
+		return 100
+	}
+	return int(min(a, b) * 100 / larger)
+}
@@ -0,0 +1,99 @@
+package compare
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want int
+	}{
+		{"identical", "a\nb\nc\n", "a\nb\nc\n", 100},
+		{"both empty", "", "", 100},
+		{"nothing in common", "a\nb\n", "c\nd\n", 0},
+		{"one line of four changed", "line1\nline2\nline3\nline4\n", "line1\nline2\nline3\nLINE4\n", 75},
+		{"line order ignored", "a\nb\nc\n", "c\nb\na\n", 100},
+		{"relative to larger file", "aaaa\n", "aaaa\nbbbbb\n", 45},
+		{"repeated lines counted once per copy", "x\nx\nx\n", "x\n", 33},
+		{"missing final newline", "a\nb", "a\nb\n", 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &renameCandidate{size: int64(len(tt.a)), lines: lineSignature([]byte(tt.a))}
+			b := &renameCandidate{size: int64(len(tt.b)), lines: lineSignature([]byte(tt.b))}
+			if got := similarity(a, b); got != tt.want {
+				t.Errorf("similarity(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+			if got := similarity(b, a); got != tt.want {
+				t.Errorf("similarity(%q, %q) = %d, want %d", tt.b, tt.a, got, tt.want)
+			}
+			if bound := sizeSimilarity(a.size, b.size); bound < tt.want {
+				t.Errorf("sizeSimilarity(%d, %d) = %d, below similarity %d", a.size, b.size, bound, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectRenames(t *testing.T) {
+	dir := t.TempDir()
+	lines := func(prefix string, n int) string {
+		var b strings.Builder
+		for i := 0; i < n; i++ {
+			b.WriteString(prefix)
+			b.WriteString(strings.Repeat("x", i%7))
+			b.WriteString("\n")
+		}
+		return b.String()
+	}
+	base := lines("line", 20)
+
+	files := map[string]string{
+		"old.txt":      base,
+		"new.txt":      base + "one more line\n",
+		"gone.txt":     lines("gone", 10),
+		"unrelated.md": lines("other", 10),
+	}
+	info := make(map[string]*FileInfo)
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		info[name] = &FileInfo{Path: path, RelPath: name, Name: name, Size: int64(len(content))}
+	}
+	for _, name := range []string{"old.txt", "gone.txt"} {
+		info[name].Status = Deleted
+	}
+	for _, name := range []string{"new.txt", "unrelated.md"} {
+		info[name].Status = New
+	}
+
+	c := NewComparator(Options{FindRenames: 50})
+	err := c.detectRenames(
+		[]*FileInfo{info["old.txt"], info["gone.txt"]},
+		[]*FileInfo{info["new.txt"], info["unrelated.md"]},
+		50,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := info["old.txt"]; got.Status != Renamed || got.Counterpart != "new.txt" || got.Similarity < 50 {
+		t.Errorf("old.txt: status %s, counterpart %q, similarity %d; want renamed to new.txt", got.Status, got.Counterpart, got.Similarity)
+	}
+	if got := info["new.txt"]; got.Status != Renamed || got.Counterpart != "old.txt" {
+		t.Errorf("new.txt: status %s, counterpart %q; want renamed from old.txt", got.Status, got.Counterpart)
+	}
+	if got := info["gone.txt"].Status; got != Deleted {
+		t.Errorf("gone.txt: status %s, want %s", got, Deleted)
+	}
+	if got := info["unrelated.md"].Status; got != New {
+		t.Errorf("unrelated.md: status %s, want %s", got, New)
+	}
+}
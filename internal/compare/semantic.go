@@ -0,0 +1,281 @@
+package compare
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SemanticFormat names a structured data format compared by decoded value
+type SemanticFormat string
+
+const (
+	SemanticJSON SemanticFormat = "json"
+	SemanticYAML SemanticFormat = "yaml"
+)
+
+// SemanticFormats lists the formats accepted by ParseSemanticFormats
+var SemanticFormats = []SemanticFormat{SemanticJSON, SemanticYAML}
+
+// semanticExtensions maps file extensions to the format they hold
+var semanticExtensions = map[string]SemanticFormat{
+	".json": SemanticJSON,
+	".yaml": SemanticYAML,
+	".yml":  SemanticYAML,
+}
+
+// ParseSemanticFormats parses a comma-separated format list such as
+// "json,yaml". An empty string yields no formats.
+func ParseSemanticFormats(value string) ([]SemanticFormat, error) {
+	var formats []SemanticFormat
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		format, err := parseSemanticFormat(name)
+		if err != nil {
+			return nil, err
+		}
+		formats = append(formats, format)
+	}
+	return formats, nil
+}
+
+func parseSemanticFormat(name string) (SemanticFormat, error) {
+	names := make([]string, len(SemanticFormats))
+	for i, format := range SemanticFormats {
+		if string(format) == name {
+			return format, nil
+		}
+		names[i] = string(format)
+	}
+	return "", fmt.Errorf("unknown format %q (supported: %s)", name, strings.Join(names, ", "))
+}
+
+// semanticFormat returns the enabled format of the file at path, if any
+func (c *Comparator) semanticFormat(path string) (SemanticFormat, bool) {
+	format, ok := semanticExtensions[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return "", false
+	}
+	for _, enabled := range c.opts.Semantic {
+		if enabled == format {
+			return format, true
+		}
+	}
+	return "", false
+}
+
+// compareSemantic re-compares a modified pair of structured data files by
+// their decoded values. Formatting-only changes make the pair Identical
+// with RawDiffers set; otherwise the differing key paths are recorded.
+// Files that do not parse keep their Modified status.
+func (c *Comparator) compareSemantic(pair filePair) error {
+	format, _ := c.semanticFormat(pair.source.Path)
+
+	sourceValue, err := decodeFile(pair.source.Path, format)
+	if err != nil {
+		return c.semanticError(err)
+	}
+	targetValue, err := decodeFile(pair.target.Path, format)
+	if err != nil {
+		return c.semanticError(err)
+	}
+
+	var paths []string
+	diffValues("", sourceValue, targetValue, &paths)
+
+	if len(paths) == 0 {
+		pair.source.Status = Identical
+		pair.source.RawDiffers = true
+		pair.target.Status = Identical
+		pair.target.RawDiffers = true
+		return nil
+	}
+	pair.source.DiffPaths = paths
+	pair.target.DiffPaths = paths
+	return nil
+}
+
+// semanticError keeps the raw comparison's verdict for files that cannot
+// be read or parsed. Only read errors fail a strict comparison.
+func (c *Comparator) semanticError(err error) error {
+	var parseErr *semanticParseError
+	if c.opts.Strict && !errors.As(err, &parseErr) {
+		return err
+	}
+	return nil
+}
+
+// semanticParseError reports content that is not valid in its format
+type semanticParseError struct {
+	path string
+	err  error
+}
+
+func (e *semanticParseError) Error() string {
+	return fmt.Sprintf("parse %s: %v", e.path, e.err)
+}
+
+// decodeFile decodes a JSON document or a stream of YAML documents
+func decodeFile(path string, format SemanticFormat) (any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case SemanticJSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		var value any
+		if err := decoder.Decode(&value); err != nil {
+			return nil, &semanticParseError{path: path, err: err}
+		}
+		var extra any
+		if err := decoder.Decode(&extra); err != io.EOF {
+			if err == nil {
+				err = errors.New("unexpected data after the top-level value")
+			}
+			return nil, &semanticParseError{path: path, err: err}
+		}
+		return normalizeValue(value), nil
+
+	default:
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		var documents []any
+		for {
+			var document any
+			err := decoder.Decode(&document)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, &semanticParseError{path: path, err: err}
+			}
+			documents = append(documents, normalizeValue(document))
+		}
+		if len(documents) == 1 {
+			return documents[0], nil
+		}
+		return documents, nil
+	}
+}
+
+// number is a decoded number in canonical form: its exact value as a
+// fraction, so that 1, 1.0 and 10e-1 compare equal while integers too
+// large for a float64 keep every digit
+type number string
+
+// normalizeValue converts decoded values to a common shape: maps keyed by
+// strings and numbers as number
+func normalizeValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			v[key] = normalizeValue(item)
+		}
+		return v
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = normalizeValue(item)
+		}
+		return m
+	case []any:
+		for i, item := range v {
+			v[i] = normalizeValue(item)
+		}
+		return v
+	case json.Number:
+		if r, ok := new(big.Rat).SetString(string(v)); ok {
+			return number(r.RatString())
+		}
+		return string(v)
+	case int:
+		return number(strconv.Itoa(v))
+	case int64:
+		return number(strconv.FormatInt(v, 10))
+	case uint64:
+		return number(strconv.FormatUint(v, 10))
+	case float64:
+		// Infinities and NaN have no exact value and stay as they are
+		if r := new(big.Rat).SetFloat64(v); r != nil {
+			return number(r.RatString())
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// diffValues appends the paths at which two decoded values differ, in a
+// jq-like notation: ".spec.replicas", ".items[2]", "." for the root
+func diffValues(path string, a, b any, paths *[]string) {
+	switch av := a.(type) {
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := make(map[string]bool)
+		for key := range av {
+			keys[key] = true
+		}
+		for key := range bv {
+			keys[key] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for key := range keys {
+			sorted = append(sorted, key)
+		}
+		sort.Strings(sorted)
+
+		for _, key := range sorted {
+			aItem, inA := av[key]
+			bItem, inB := bv[key]
+			if inA != inB {
+				*paths = append(*paths, path+"."+key)
+				continue
+			}
+			diffValues(path+"."+key, aItem, bItem, paths)
+		}
+		return
+
+	case []any:
+		bv, ok := b.([]any)
+		if !ok {
+			break
+		}
+		for i := 0; i < max(len(av), len(bv)); i++ {
+			itemPath := fmt.Sprintf("%s[%d]", path, i)
+			if i >= len(av) || i >= len(bv) {
+				*paths = append(*paths, itemPath)
+				continue
+			}
+			diffValues(itemPath, av[i], bv[i], paths)
+		}
+		return
+
+	default:
+		if a == b {
+			return
+		}
+	}
+
+	if path == "" {
+		path = "."
+	}
+	*paths = append(*paths, path)
+}
@@ -0,0 +1,102 @@
+package compare
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSemanticFormats(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []SemanticFormat
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"json", []SemanticFormat{SemanticJSON}, false},
+		{"json, yaml", []SemanticFormat{SemanticJSON, SemanticYAML}, false},
+		{"json,toml", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSemanticFormats(tt.value)
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseSemanticFormats(%q) = %v, %v; want %v, error %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	_, err := ParseSemanticFormats("toml")
+	for _, format := range SemanticFormats {
+		if err == nil || !strings.Contains(err.Error(), string(format)) {
+			t.Errorf("error %v does not list format %q", err, format)
+		}
+	}
+}
+
+func TestCompareSemantic(t *testing.T) {
+	both := []SemanticFormat{SemanticJSON, SemanticYAML}
+	tests := []struct {
+		name           string
+		file           string
+		formats        []SemanticFormat
+		source, target string
+		want           FileStatus
+		wantRawDiffers bool
+		wantPaths      []string
+	}{
+		{"json formatting", "a.json", both, `{"a": 1, "b": [1, 2]}`, "{\n  \"b\": [1,2],\n  \"a\": 1\n}\n", Identical, true, nil},
+		{"json number forms", "a.json", both, `{"n": 1, "f": 0.5}`, `{"n": 1.0, "f": 5e-1}`, Identical, true, nil},
+		{"json large integers", "a.json", both, `{"id": 9007199254740993}`, `{"id": 9007199254740992}`, Modified, false, []string{".id"}},
+		{"json changed values", "a.json", both, `{"a": 1, "b": {"c": [1, 2]}, "d": 1}`, `{"a": 2, "b": {"c": [1, 3, 4]}, "e": 1}`, Modified, false,
+			[]string{".a", ".b.c[1]", ".b.c[2]", ".d", ".e"}},
+		{"json type changed", "a.json", both, `{"a": "1"}`, `{"a": 1}`, Modified, false, []string{".a"}},
+		{"json root changed", "a.json", both, `[1]`, `{"a": 1}`, Modified, false, []string{"."}},
+		{"json trailing value", "a.json", both, `{"a": 1}`, "{\"a\": 1}\n{\"a\": 1}", Modified, false, nil},
+		{"json invalid", "a.json", both, `{"a": 1}`, `{"a": 1`, Modified, false, nil},
+		{"json not enabled", "a.json", []SemanticFormat{SemanticYAML}, `{"a": 1}`, `{ "a": 1 }`, Modified, false, nil},
+		{"yaml formatting", "a.yaml", both, "a: 1\nb: [x, y]\n", "# comment\nb:\n  - x\n  - y\na: 1\n", Identical, true, nil},
+		{"yml extension", "a.yml", both, "a: 1\n", "a:   1\n", Identical, true, nil},
+		{"yaml number forms", "a.yaml", both, "n: 1\nf: 0.5\n", "n: 0x1\nf: .5\n", Identical, true, nil},
+		{"yaml changed value", "a.yaml", both, "spec:\n  replicas: 1\n", "spec:\n  replicas: 2\n", Modified, false, []string{".spec.replicas"}},
+		{"yaml documents", "a.yaml", both, "a: 1\n---\nb: 2\n", "a: 1\n---\nb: 3\n", Modified, false, []string{"[1].b"}},
+		{"yaml integer keys", "a.yaml", both, "1: a\n", "1:   a\n", Identical, true, nil},
+		{"yaml invalid", "a.yaml", both, "a: 1\n", "a: [1\n", Modified, false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{Mode: SmartMode, Hash: sha256File, Semantic: tt.formats}
+			source, target := comparePair(t, opts, tt.file, tt.source, tt.target, baseTime)
+			for _, file := range []*FileInfo{source, target} {
+				if file.Status != tt.want || file.RawDiffers != tt.wantRawDiffers || !reflect.DeepEqual(file.DiffPaths, tt.wantPaths) {
+					t.Errorf("status %s, raw differs %v, paths %q; want %s, %v, %q",
+						file.Status, file.RawDiffers, file.DiffPaths, tt.want, tt.wantRawDiffers, tt.wantPaths)
+				}
+			}
+		})
+	}
+}
+
+// Like text normalization, semantic comparison needs the content to have
+// been compared first
+func TestCompareSemanticOnlyAfterContentComparison(t *testing.T) {
+	tests := []struct {
+		mode           ComparisonMode
+		want           FileStatus
+		wantRawDiffers bool
+	}{
+		{QuickMode, Modified, false},
+		{FilenameMode, Identical, false},
+		{HashMode, Identical, true},
+		{BytesMode, Identical, true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			opts := Options{Mode: tt.mode, Semantic: []SemanticFormat{SemanticJSON}}
+			source, _ := comparePair(t, opts, "a.json", `{"a": 1}`, `{"a":1}`, baseTime.Add(time.Hour))
+			if source.Status != tt.want || source.RawDiffers != tt.wantRawDiffers {
+				t.Errorf("status %s, raw differs %v; want %s, %v", source.Status, source.RawDiffers, tt.want, tt.wantRawDiffers)
+			}
+		})
+	}
+}
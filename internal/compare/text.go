@@ -0,0 +1,126 @@
+package compare
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+)
+
+// sniffLen is how much of a file is inspected to tell text from binary,
+// the same amount git looks at
+const sniffLen = 8000
+
+// IsBinary reports whether data looks like binary content: like git, any
+// NUL byte in the first sniffLen bytes makes a file binary
+func IsBinary(data []byte) bool {
+	if len(data) > sniffLen {
+		data = data[:sniffLen]
+	}
+	return bytes.IndexByte(data, 0) >= 0
+}
+
+// IsBinaryFile reports whether the file at path looks like binary content
+func IsBinaryFile(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, err
+	}
+	return IsBinary(head[:n]), nil
+}
+
+// TextOptions select the differences ignored when comparing text files
+type TextOptions struct {
+	IgnoreEOL           bool // CRLF and LF line endings are equal
+	IgnoreWhitespace    bool // All spaces and tabs within a line are ignored
+	IgnoreTrailingSpace bool // Spaces and tabs at the end of a line are ignored
+}
+
+// enabled reports whether any normalization is requested
+func (o TextOptions) enabled() bool {
+	return o.IgnoreEOL || o.IgnoreWhitespace || o.IgnoreTrailingSpace
+}
+
+// normalizeLine applies the options to a line read with its line ending
+func (o TextOptions) normalizeLine(line []byte) []byte {
+	body := line
+	eol := []byte(nil)
+	if n := len(body); n > 0 && body[n-1] == '\n' {
+		body, eol = body[:n-1], body[n-1:]
+		if n := len(body); n > 0 && body[n-1] == '\r' {
+			body, eol = body[:n-1], line[n-1:]
+		}
+	}
+
+	if o.IgnoreEOL && eol != nil {
+		eol = []byte{'\n'}
+	}
+	if o.IgnoreTrailingSpace {
+		body = bytes.TrimRight(body, " \t")
+	}
+	if o.IgnoreWhitespace {
+		body = bytes.Map(func(r rune) rune {
+			switch r {
+			case ' ', '\t', '\v', '\f', '\r':
+				return -1
+			}
+			return r
+		}, body)
+	}
+
+	return append(body[:len(body):len(body)], eol...)
+}
+
+// equalText reports whether two files are text and equal once normalized.
+// Binary files are never considered equal here.
+func (o TextOptions) equalText(sourcePath, targetPath string) (bool, error) {
+	source, err := os.Open(sourcePath)
+	if err != nil {
+		return false, err
+	}
+	defer source.Close()
+
+	target, err := os.Open(targetPath)
+	if err != nil {
+		return false, err
+	}
+	defer target.Close()
+
+	sourceReader := bufio.NewReaderSize(source, sniffLen)
+	targetReader := bufio.NewReaderSize(target, sniffLen)
+
+	for _, reader := range []*bufio.Reader{sourceReader, targetReader} {
+		head, err := reader.Peek(sniffLen)
+		if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+			return false, err
+		}
+		if IsBinary(head) {
+			return false, nil
+		}
+	}
+
+	for {
+		sourceLine, sourceErr := sourceReader.ReadBytes('\n')
+		if sourceErr != nil && sourceErr != io.EOF {
+			return false, sourceErr
+		}
+		targetLine, targetErr := targetReader.ReadBytes('\n')
+		if targetErr != nil && targetErr != io.EOF {
+			return false, targetErr
+		}
+
+		if !bytes.Equal(o.normalizeLine(sourceLine), o.normalizeLine(targetLine)) {
+			return false, nil
+		}
+		if sourceErr == io.EOF || targetErr == io.EOF {
+			return sourceErr == targetErr, nil
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package compare
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsBinary(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want bool
+	}{
+		{"empty", "", false},
+		{"text", "hello\nworld\n", false},
+		{"utf-8", "héllo wörld\n", false},
+		{"nul byte", "ab\x00cd", true},
+		{"nul byte past the sniffed head", strings.Repeat("a", sniffLen) + "\x00", false},
+	}
+	for _, tt := range tests {
+		if got := IsBinary([]byte(tt.data)); got != tt.want {
+			t.Errorf("%s: IsBinary = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeLine(t *testing.T) {
+	tests := []struct {
+		name string
+		opts TextOptions
+		line string
+		want string
+	}{
+		{"no options", TextOptions{}, "a b \r\n", "a b \r\n"},
+		{"crlf", TextOptions{IgnoreEOL: true}, "a b\r\n", "a b\n"},
+		{"lf kept", TextOptions{IgnoreEOL: true}, "a b\n", "a b\n"},
+		{"no line ending", TextOptions{IgnoreEOL: true}, "a b", "a b"},
+		{"trailing space", TextOptions{IgnoreTrailingSpace: true}, "a b \t\n", "a b\n"},
+		{"trailing space before crlf", TextOptions{IgnoreTrailingSpace: true}, "a b  \r\n", "a b\r\n"},
+		{"trailing space keeps leading", TextOptions{IgnoreTrailingSpace: true}, "  a\n", "  a\n"},
+		{"all whitespace", TextOptions{IgnoreWhitespace: true}, " a\t b \n", "ab\n"},
+		{"all whitespace and crlf", TextOptions{IgnoreWhitespace: true, IgnoreEOL: true}, "a b \r\n", "ab\n"},
+	}
+	for _, tt := range tests {
+		if got := string(tt.opts.normalizeLine([]byte(tt.line))); got != tt.want {
+			t.Errorf("%s: normalizeLine(%q) = %q, want %q", tt.name, tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestCompareText(t *testing.T) {
+	eol := TextOptions{IgnoreEOL: true}
+	tests := []struct {
+		name           string
+		opts           TextOptions
+		source, target string
+		want           FileStatus
+		wantRawDiffers bool
+	}{
+		{"crlf ignored", eol, "a\r\nb\r\n", "a\nb\n", Identical, true},
+		{"crlf not ignored", TextOptions{IgnoreTrailingSpace: true}, "a\r\nb\r\n", "a\nb\n", Modified, false},
+		{"content still differs", eol, "a\r\nb\r\n", "a\nc\n", Modified, false},
+		{"extra line", eol, "a\r\n", "a\nb\n", Modified, false},
+		{"missing final newline matters", eol, "a\r\nb", "a\nb\n", Modified, false},
+		{"trailing space ignored", TextOptions{IgnoreTrailingSpace: true}, "a  \nb\n", "a\nb\t\n", Identical, true},
+		{"indentation ignored", TextOptions{IgnoreWhitespace: true}, "if x {\n\treturn\n}\n", "if x {\n    return\n}\n", Identical, true},
+		{"binary never normalized", eol, "a\x00\r\n", "a\x00\n", Modified, false},
+		{"raw equal", eol, "same\n", "same\n", Identical, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{Mode: SmartMode, Hash: sha256File, Text: tt.opts}
+			source, target := comparePair(t, opts, "file.txt", tt.source, tt.target, baseTime)
+			for _, file := range []*FileInfo{source, target} {
+				if file.Status != tt.want || file.RawDiffers != tt.wantRawDiffers {
+					t.Errorf("status %s, raw differs %v; want %s, %v", file.Status, file.RawDiffers, tt.want, tt.wantRawDiffers)
+				}
+			}
+		})
+	}
+}
+
+// Modes that do not read content must not report a pair as equal once
+// normalized: in QuickMode the bytes may well be the same
+func TestCompareTextOnlyAfterContentComparison(t *testing.T) {
+	later := baseTime.Add(time.Hour)
+	tests := []struct {
+		name           string
+		mode           ComparisonMode
+		source, target string
+		targetTime     time.Time
+		want           FileStatus
+		wantRawDiffers bool
+	}{
+		{"quick mtime only", QuickMode, "a\nb\n", "a\nb\n", later, Modified, false},
+		{"quick crlf", QuickMode, "a\r\nb\r\n", "a\nb\n", later, Modified, false},
+		{"filename", FilenameMode, "a\r\nb\r\n", "a\nb\n", later, Identical, false},
+		{"hash", HashMode, "a\r\nb\r\n", "a\nb\n", later, Identical, true},
+		{"bytes", BytesMode, "a\r\nb\r\n", "a\nb\n", later, Identical, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{Mode: tt.mode, Text: TextOptions{IgnoreEOL: true}}
+			source, _ := comparePair(t, opts, "file.txt", tt.source, tt.target, tt.targetTime)
+			if source.Status != tt.want || source.RawDiffers != tt.wantRawDiffers {
+				t.Errorf("status %s, raw differs %v; want %s, %v", source.Status, source.RawDiffers, tt.want, tt.wantRawDiffers)
+			}
+		})
+	}
+}
+
+func TestEqualTextUnreadable(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.txt"), "a\n", baseTime)
+	if _, err := (TextOptions{IgnoreEOL: true}).equalText(filepath.Join(dir, "a.txt"), filepath.Join(dir, "missing.txt")); err == nil {
+		t.Error("equalText with a missing file succeeded")
+	}
+}
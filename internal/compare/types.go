@@ -1,13 +1,39 @@
 package compare
 
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
 // ComparisonMode defines how files should be compared
 type ComparisonMode string
 
 const (
 	HashMode     ComparisonMode = "hash"
 	FilenameMode ComparisonMode = "filename"
+	QuickMode    ComparisonMode = "quick" // Size and modification time, like rsync
+	SmartMode    ComparisonMode = "smart" // Size first, hash only when sizes match
+	BytesMode    ComparisonMode = "bytes" // Byte-by-byte, recording the first difference
 )
 
+// ComparisonModes lists the modes accepted by ParseComparisonMode, default
+// first
+var ComparisonModes = []ComparisonMode{HashMode, SmartMode, BytesMode, QuickMode, FilenameMode}
+
+// ParseComparisonMode returns the mode called name, failing if it is unknown
+func ParseComparisonMode(name string) (ComparisonMode, error) {
+	names := make([]string, len(ComparisonModes))
+	for i, mode := range ComparisonModes {
+		if string(mode) == name {
+			return mode, nil
+		}
+		names[i] = string(mode)
+	}
+	return "", fmt.Errorf("unknown mode %q (supported: %s)", name, strings.Join(names, ", "))
+}
+
 // FileStatus represents the comparison status of a file
 type FileStatus string
 
@@ -16,19 +42,56 @@ const (
 	Modified  FileStatus = "modified"
 	New       FileStatus = "new"
 	Deleted   FileStatus = "deleted"
+	Error     FileStatus = "error" // The entry could not be read; see FileInfo.ErrorMessage
+
+	// TypeChanged marks a path that is a file on one side and a directory
+	// (or an unfollowed symlink) on the other
+	TypeChanged FileStatus = "type-changed"
+
+	// Moved marks a file that only exists at one path in the source and at
+	// another in the target with the same content; see FileInfo.Counterpart
+	Moved FileStatus = "moved"
+
+	// Renamed marks a text file moved and edited: it only exists at one
+	// path in the source and at another in the target with similar
+	// content; see FileInfo.Counterpart and FileInfo.Similarity
+	Renamed FileStatus = "renamed"
+
+	// ContainsChanges marks a directory present on both sides with at
+	// least one entry below it that is not Identical
+	ContainsChanges FileStatus = "contains-changes"
+
+	// MetaChanged marks entries with identical content whose compared
+	// attributes differ; see FileInfo.MetaDiff
+	MetaChanged FileStatus = "meta-changed"
 )
 
 // FileInfo represents a file or directory in the comparison
 type FileInfo struct {
-	Path     string
-	RelPath  string
-	Hash     string
-	Status   FileStatus
-	IsDir    bool
-	Children []*FileInfo
-	Parent   *FileInfo // Added for tree navigation in TUI
-	Name     string    // Base name for display
-	Expanded bool      // Track expand/collapse state in TUI
+	Path          string
+	RelPath       string
+	Hash          string
+	HashAlgorithm string // Algorithm that produced Hash
+	Size          int64
+	ModTime       time.Time
+	DiffOffset    int64 // First differing byte; only set for Modified files in BytesMode
+	Mode          os.FileMode
+	UID           int        // Owning user, where the platform has one
+	GID           int        // Owning group, where the platform has one
+	MetaDiff      []MetaAttr // Attributes that differ, when Status is MetaChanged
+	RawDiffers    bool       // Identical only once normalized; the raw bytes differ
+	DiffPaths     []string   // Differing key paths of structured data files (--semantic)
+	Status        FileStatus
+	ErrorMessage  string // Why the entry could not be read, when Status is Error
+	Counterpart   string // RelPath of the other end of a move, when Status is Moved or Renamed
+	Similarity    int    // Content similarity in percent, when Status is Renamed
+	IsDir         bool
+	IsSymlink     bool   // Unfollowed symbolic link, compared by LinkTarget
+	LinkTarget    string // Where a symbolic link points, followed or not
+	Children      []*FileInfo
+	Parent        *FileInfo // Added for tree navigation in TUI
+	Name          string    // Base name for display
+	Expanded      bool      // Track expand/collapse state in TUI
 }
 
 // ComparisonResult contains the complete comparison results
@@ -40,5 +103,26 @@ type ComparisonResult struct {
 	SourceTree     *FileInfo // Tree structure for TUI
 	TargetTree     *FileInfo // Tree structure for TUI
 	Mode           ComparisonMode
+	HashAlgorithm  string // Algorithm behind every Hash, empty if nothing was hashed
 	ExcludePattern []string
+	CompareMeta    []MetaAttr    // Attributes compared besides content
+	MtimeTolerance time.Duration // Largest modification time difference treated as equal
+}
+
+// SetError marks the entry as unreadable, recording why
+func (f *FileInfo) SetError(err error) {
+	f.Status = Error
+	f.ErrorMessage = err.Error()
+}
+
+// EntryType returns "dir", "symlink" or "file"
+func (f *FileInfo) EntryType() string {
+	switch {
+	case f.IsSymlink:
+		return "symlink"
+	case f.IsDir:
+		return "dir"
+	default:
+		return "file"
+	}
 }
@@ -0,0 +1,189 @@
+// Package fsops changes the compared trees on behalf of the TUI: it copies
+// entries from one side to the other, preserving their permission bits and
+// modification times, and deletes them or moves them to a trash directory.
+package fsops
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// modeBits are the mode bits carried over to copies: permissions plus the
+// setuid, setgid and sticky bits
+const modeBits = os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+
+// CopyFile copies the regular file at src to dst, replacing dst. The copy
+// is written to a temporary file next to dst and renamed into place, so
+// dst is never left half-written.
+func CopyFile(src, dst string) error {
+	source, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	info, err := source.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", src)
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".*")
+	if err != nil {
+		return err
+	}
+	tempPath := temp.Name()
+
+	_, err = io.Copy(temp, source)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tempPath, info.Mode()&modeBits)
+	}
+	if err == nil {
+		err = os.Chtimes(tempPath, info.ModTime(), info.ModTime())
+	}
+	if err == nil {
+		err = os.Rename(tempPath, dst)
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	return nil
+}
+
+// CopySymlink creates a link at dst pointing where the link at src does,
+// replacing any file or link at dst
+func CopySymlink(src, dst string) error {
+	target, err := os.Readlink(src)
+	if err != nil {
+		return err
+	}
+
+	if info, err := os.Lstat(dst); err == nil {
+		if info.IsDir() {
+			return fmt.Errorf("%s is a directory", dst)
+		}
+		if err := os.Remove(dst); err != nil {
+			return err
+		}
+	}
+	return os.Symlink(target, dst)
+}
+
+// MakeDir creates the directory dst with the permission and special bits
+// of mode, or sets them if it already exists
+func MakeDir(dst string, mode os.FileMode) error {
+	if err := os.Mkdir(dst, mode.Perm()); err != nil {
+		if !os.IsExist(err) {
+			return err
+		}
+		if info, statErr := os.Lstat(dst); statErr != nil || !info.IsDir() {
+			return err
+		}
+	}
+	return os.Chmod(dst, mode&modeBits)
+}
+
+// SetOwner sets the user and group owning the file, link or directory at
+// path, without following links. Usually only root may do so. Changing
+// owners clears the setuid and setgid bits, so the bits of mode are set
+// again afterwards.
+func SetOwner(path string, uid, gid int, mode os.FileMode) error {
+	if err := os.Lchown(path, uid, gid); err != nil {
+		return err
+	}
+	if mode&os.ModeSymlink != 0 {
+		return nil
+	}
+	return os.Chmod(path, mode&modeBits)
+}
+
+// SetModTime sets the modification time of the file or directory at path
+func SetModTime(path string, modTime time.Time) error {
+	return os.Chtimes(path, modTime, modTime)
+}
+
+// Remove deletes the file, link or empty directory at path
+func Remove(path string) error {
+	return os.Remove(path)
+}
+
+// IsEmptyDir reports whether the directory at path has no entries
+func IsEmptyDir(path string) (bool, error) {
+	dir, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer dir.Close()
+
+	if _, err := dir.Readdirnames(1); err == io.EOF {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+// MoveToTrash moves the file, link or empty directory at path to relPath
+// below trashDir, creating the directories in between, and returns where
+// it went. A file already in the trash at that path is kept; a numeric
+// suffix is added instead. A directory is recreated in the trash, merging
+// with one already there, and then removed. Across file systems a file is
+// copied and then removed.
+func MoveToTrash(path, trashDir, relPath string) (string, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return "", err
+	}
+
+	dst := filepath.Join(trashDir, relPath)
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return "", err
+	}
+
+	if info.IsDir() {
+		if err := MakeDir(dst, info.Mode()); err != nil {
+			return "", err
+		}
+		if err := SetModTime(dst, info.ModTime()); err != nil {
+			return "", err
+		}
+		return dst, os.Remove(path)
+	}
+
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(dst); os.IsNotExist(err) {
+			break
+		}
+		dst = fmt.Sprintf("%s.%d", filepath.Join(trashDir, relPath), i)
+	}
+
+	err = os.Rename(path, dst)
+	if err == nil {
+		return dst, nil
+	}
+	if !errors.Is(err, syscall.EXDEV) {
+		return "", err
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		err = CopySymlink(path, dst)
+	} else {
+		err = CopyFile(path, dst)
+	}
+	if err != nil {
+		os.Remove(dst)
+		return "", err
+	}
+	return dst, os.Remove(path)
+}
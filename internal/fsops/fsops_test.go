@@ -0,0 +1,354 @@
+package fsops
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var modTime = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// writeFile creates the file at path with content, mode and modTime
+func writeFile(t *testing.T, path, content string, mode os.FileMode) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readFile returns the content of the file at path
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestCopyFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     os.FileMode
+		existing bool
+	}{
+		{"new file", 0o644, false},
+		{"replaces existing", 0o640, true},
+		{"executable", 0o755, false},
+		{"setuid and setgid", 0o755 | os.ModeSetuid | os.ModeSetgid, false},
+		{"sticky", 0o644 | os.ModeSticky, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+			writeFile(t, src, "new content", tt.mode)
+			if tt.existing {
+				writeFile(t, dst, "old", 0o600)
+			}
+
+			if err := CopyFile(src, dst); err != nil {
+				t.Fatal(err)
+			}
+
+			info, err := os.Stat(dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := readFile(t, dst); got != "new content" {
+				t.Errorf("content %q, want %q", got, "new content")
+			}
+			if got := info.Mode() & modeBits; got != tt.mode {
+				t.Errorf("mode %v, want %v", got, tt.mode)
+			}
+			if !info.ModTime().Equal(modTime) {
+				t.Errorf("mod time %v, want %v", info.ModTime(), modTime)
+			}
+
+			// Nothing is left behind but the copy
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 2 {
+				t.Errorf("%d entries next to the copy, want 2", len(entries))
+			}
+		})
+	}
+}
+
+func TestCopyFileFailures(t *testing.T) {
+	dir := t.TempDir()
+	if err := CopyFile(dir, filepath.Join(dir, "copy")); err == nil {
+		t.Error("copying a directory succeeded")
+	}
+	if err := CopyFile(filepath.Join(dir, "missing"), filepath.Join(dir, "copy")); err == nil {
+		t.Error("copying a missing file succeeded")
+	}
+
+	writeFile(t, filepath.Join(dir, "src"), "content", 0o644)
+	if err := os.Mkdir(filepath.Join(dir, "dst"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := CopyFile(filepath.Join(dir, "src"), filepath.Join(dir, "dst")); err == nil {
+		t.Error("replacing a directory with a file succeeded")
+	}
+
+	// The temporary copy is cleaned up
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if name := entry.Name(); name != "src" && name != "dst" {
+			t.Errorf("failed copy left %s behind", name)
+		}
+	}
+}
+
+func TestCopySymlink(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "link")
+	if err := os.Symlink("target/path", src); err != nil {
+		t.Skipf("cannot create symlinks: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		existing func(dst string) error
+		wantErr  bool
+	}{
+		{"new link", func(string) error { return nil }, false},
+		{"replaces file", func(dst string) error { return os.WriteFile(dst, nil, 0o644) }, false},
+		{"replaces link", func(dst string) error { return os.Symlink("elsewhere", dst) }, false},
+		{"refuses directory", func(dst string) error { return os.Mkdir(dst, 0o755) }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := filepath.Join(t.TempDir(), "copy")
+			if err := tt.existing(dst); err != nil {
+				t.Fatal(err)
+			}
+
+			err := CopySymlink(src, dst)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("CopySymlink succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if target, err := os.Readlink(dst); err != nil || target != "target/path" {
+				t.Errorf("copy points to %q (%v), want %q", target, err, "target/path")
+			}
+		})
+	}
+}
+
+func TestMakeDir(t *testing.T) {
+	dir := t.TempDir()
+
+	created := filepath.Join(dir, "created")
+	if err := MakeDir(created, os.ModeDir|0o750|os.ModeSetgid|os.ModeSticky); err != nil {
+		t.Fatal(err)
+	}
+	existing := filepath.Join(dir, "existing")
+	if err := os.Mkdir(existing, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := MakeDir(existing, os.ModeDir|0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, want := range map[string]os.FileMode{
+		created:  0o750 | os.ModeSetgid | os.ModeSticky,
+		existing: 0o755,
+	} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !info.IsDir() || info.Mode()&modeBits != want {
+			t.Errorf("%s: mode %v, want directory with %v", path, info.Mode(), want)
+		}
+	}
+
+	file := filepath.Join(dir, "file")
+	writeFile(t, file, "", 0o644)
+	if err := MakeDir(file, os.ModeDir|0o755); err == nil {
+		t.Error("MakeDir over a file succeeded")
+	}
+}
+
+func TestSetOwner(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing owners requires root")
+	}
+
+	path := filepath.Join(t.TempDir(), "file")
+	mode := 0o755 | os.ModeSetuid | os.ModeSetgid
+	writeFile(t, path, "content", mode)
+
+	if err := SetOwner(path, 1000, 1000, mode); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Changing owners clears setuid and setgid; they must be back
+	if got := info.Mode() & modeBits; got != mode {
+		t.Errorf("mode %v after changing owners, want %v", got, mode)
+	}
+}
+
+func TestIsEmptyDir(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty")
+	if err := os.Mkdir(empty, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, "file"), "", 0o644)
+
+	tests := []struct {
+		path    string
+		want    bool
+		wantErr bool
+	}{
+		{empty, true, false},
+		{dir, false, false},
+		{filepath.Join(dir, "missing"), false, true},
+	}
+	for _, tt := range tests {
+		got, err := IsEmptyDir(tt.path)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("IsEmptyDir(%s) = %v, %v; want %v, error %v", tt.path, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestRemove(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "file"), "", 0o644)
+	if err := os.MkdirAll(filepath.Join(dir, "full", "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"file", filepath.Join("full", "sub")} {
+		if err := Remove(filepath.Join(dir, name)); err != nil {
+			t.Errorf("Remove(%s): %v", name, err)
+		}
+	}
+	writeFile(t, filepath.Join(dir, "full", "file"), "", 0o644)
+	if err := Remove(filepath.Join(dir, "full")); err == nil {
+		t.Error("removing a folder with entries succeeded")
+	}
+}
+
+func TestMoveToTrash(t *testing.T) {
+	dir, trash := t.TempDir(), t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.txt"), "first", 0o640)
+
+	dst, err := MoveToTrash(filepath.Join(dir, "a.txt"), trash, filepath.Join("source", "sub", "a.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(trash, "source", "sub", "a.txt"); dst != want {
+		t.Errorf("moved to %s, want %s", dst, want)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "a.txt")); !os.IsNotExist(err) {
+		t.Error("a.txt is still in place")
+	}
+	if got := readFile(t, dst); got != "first" {
+		t.Errorf("trashed content %q, want %q", got, "first")
+	}
+
+	// Files already in the trash are kept
+	for i, content := range []string{"second", "third"} {
+		writeFile(t, filepath.Join(dir, "a.txt"), content, 0o644)
+		dst, err := MoveToTrash(filepath.Join(dir, "a.txt"), trash, filepath.Join("source", "sub", "a.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := filepath.Join(trash, "source", "sub", "a.txt") + []string{".1", ".2"}[i]
+		if dst != want || readFile(t, dst) != content {
+			t.Errorf("moved %q to %s, want %s", content, dst, want)
+		}
+	}
+	if got := readFile(t, filepath.Join(trash, "source", "sub", "a.txt")); got != "first" {
+		t.Errorf("first trashed file now holds %q", got)
+	}
+
+	// Links are moved as links
+	if err := os.Symlink("a.txt", filepath.Join(dir, "link")); err == nil {
+		dst, err := MoveToTrash(filepath.Join(dir, "link"), trash, "link")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if target, err := os.Readlink(dst); err != nil || target != "a.txt" {
+			t.Errorf("trashed link points to %q (%v), want %q", target, err, "a.txt")
+		}
+	}
+}
+
+func TestMoveDirToTrash(t *testing.T) {
+	dir, trash := t.TempDir(), t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(sub, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	// A folder already in the trash is merged with
+	existing := filepath.Join(trash, "target", "sub")
+	if err := os.MkdirAll(existing, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(existing, "earlier.txt"), "earlier", 0o644)
+
+	dst, err := MoveToTrash(sub, trash, filepath.Join("target", "sub"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dst != existing {
+		t.Errorf("moved to %s, want %s", dst, existing)
+	}
+	if _, err := os.Lstat(sub); !os.IsNotExist(err) {
+		t.Error("sub is still in place")
+	}
+	info, err := os.Stat(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode()&modeBits != 0o750 || !info.ModTime().Equal(modTime) {
+		t.Errorf("trashed folder has mode %v, time %v; want %v, %v", info.Mode()&modeBits, info.ModTime(), os.FileMode(0o750), modTime)
+	}
+	if got := readFile(t, filepath.Join(existing, "earlier.txt")); got != "earlier" {
+		t.Errorf("earlier trashed file now holds %q", got)
+	}
+
+	// Folders with entries are not moved
+	full := filepath.Join(dir, "full")
+	if err := os.Mkdir(full, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(full, "file"), "", 0o644)
+	if _, err := MoveToTrash(full, trash, "full"); err == nil {
+		t.Error("moving a folder with entries to the trash succeeded")
+	}
+	if _, err := os.Lstat(filepath.Join(full, "file")); err != nil {
+		t.Errorf("entry of a folder left in place: %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+package ignore
+
+import (
+	"os"
+	"path"
+	"strings"
+)
+
+// Result is the outcome of matching a path against a Matcher
+type Result int
+
+const (
+	NoMatch Result = iota // No rule applies to the path
+	Ignore                // The last matching rule excludes the path
+	Include               // The last matching rule is a negation (!pattern)
+)
+
+// rule is a single parsed gitignore pattern
+type rule struct {
+	pattern  string   // Pattern as written, for diagnostics
+	base     string   // Slash-separated directory the rule is scoped to, "" for the root
+	segments []string // Pattern split on "/", with "**" kept as its own segment
+	negate   bool     // Pattern started with "!"
+	dirOnly  bool     // Pattern ended with "/"
+}
+
+// Matcher evaluates gitignore-style rules. Rules are kept in the order they
+// were added and the last one matching a path decides the result, so a
+// later "!keep.log" re-includes what an earlier "*.log" excluded.
+type Matcher struct {
+	rules []rule
+}
+
+// New creates a matcher from patterns scoped to the root
+func New(patterns []string) *Matcher {
+	m := &Matcher{}
+	m.Add("", patterns)
+	return m
+}
+
+// Add appends patterns scoped to base, a slash-separated directory relative
+// to the root ("" or "." for the root itself). Blank lines and comments are
+// skipped, so the lines of an ignore file can be passed as they are.
+func (m *Matcher) Add(base string, patterns []string) {
+	base = strings.Trim(path.Clean("/"+base), "/")
+	for _, pattern := range patterns {
+		if r, ok := parseRule(pattern, base); ok {
+			m.rules = append(m.rules, r)
+		}
+	}
+}
+
+// Len returns the number of rules in the matcher
+func (m *Matcher) Len() int {
+	return len(m.rules)
+}
+
+// Match reports how the rules apply to relPath, a slash-separated path
+// relative to the root. isDir selects whether directory-only rules apply.
+func (m *Matcher) Match(relPath string, isDir bool) Result {
+	relPath = strings.Trim(path.Clean("/"+relPath), "/")
+	if relPath == "" {
+		return NoMatch
+	}
+
+	parts := strings.Split(relPath, "/")
+	for i := len(m.rules) - 1; i >= 0; i-- {
+		if m.rules[i].matches(parts, isDir) {
+			if m.rules[i].negate {
+				return Include
+			}
+			return Ignore
+		}
+	}
+	return NoMatch
+}
+
+// Ignored reports whether relPath is excluded by the rules
+func (m *Matcher) Ignored(relPath string, isDir bool) bool {
+	return m.Match(relPath, isDir) == Ignore
+}
+
+// parseRule parses one gitignore line. It returns false for blank lines,
+// comments and patterns that can never match.
+func parseRule(line, base string) (rule, bool) {
+	line = strings.TrimSuffix(line, "\r")
+	line = trimTrailingSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule{}, false
+	}
+
+	r := rule{pattern: line, base: base}
+
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return rule{}, false
+	}
+
+	// A slash at the start or in the middle anchors the pattern to base;
+	// otherwise it matches a name at any depth below base.
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimLeft(line, "/")
+	if line == "" {
+		return rule{}, false
+	}
+
+	for _, segment := range strings.Split(line, "/") {
+		if segment == "" {
+			continue
+		}
+		// Consecutive "**" segments are equivalent to a single one
+		if segment == "**" && len(r.segments) > 0 && r.segments[len(r.segments)-1] == "**" {
+			continue
+		}
+		r.segments = append(r.segments, segment)
+	}
+	if !anchored && r.segments[0] != "**" {
+		r.segments = append([]string{"**"}, r.segments...)
+	}
+
+	return r, true
+}
+
+// trimTrailingSpace removes trailing spaces unless they are escaped with a
+// backslash
+func trimTrailingSpace(line string) string {
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	return line
+}
+
+// matches reports whether the rule applies to a path given as segments
+// relative to the root
+func (r rule) matches(parts []string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+
+	if r.base != "" {
+		baseParts := strings.Split(r.base, "/")
+		if len(parts) <= len(baseParts) {
+			return false
+		}
+		for i, part := range baseParts {
+			if parts[i] != part {
+				return false
+			}
+		}
+		parts = parts[len(baseParts):]
+	}
+
+	return matchSegments(r.segments, parts)
+}
+
+// matchSegments matches pattern segments against path segments. A leading
+// or inner "**" matches zero or more directories; a trailing "**" matches
+// everything inside, but not the directory itself.
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			if len(rest) == 0 {
+				return len(parts) > 0
+			}
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(rest, parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], parts[0]); err != nil || !ok {
+			return false
+		}
+		pattern = pattern[1:]
+		parts = parts[1:]
+	}
+	return len(parts) == 0
+}
+
+// ReadPatterns reads an ignore file and returns its lines. Comments and
+// blank lines are kept; Matcher.Add skips them.
+func ReadPatterns(filename string) ([]string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(string(data), "\n"), nil
+}
@@ -0,0 +1,102 @@
+package ignore
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		isDir    bool
+		want     Result
+	}{
+		{"anchored glob", []string{"build/*.o"}, "build/main.o", false, Ignore},
+		{"anchored glob not nested", []string{"build/*.o"}, "src/build/main.o", false, NoMatch},
+		{"anchored glob one level", []string{"build/*.o"}, "build/sub/main.o", false, NoMatch},
+		{"leading ** at root", []string{"**/node_modules"}, "node_modules", true, Ignore},
+		{"leading ** nested", []string{"**/node_modules"}, "a/b/node_modules", true, Ignore},
+		{"leading slash at root", []string{"/dist"}, "dist", true, Ignore},
+		{"leading slash not nested", []string{"/dist"}, "src/dist", true, NoMatch},
+		{"unanchored name at any depth", []string{"*.log"}, "a/b/debug.log", false, Ignore},
+		{"negation re-includes", []string{"*.log", "!keep.log"}, "keep.log", false, Include},
+		{"negation leaves others", []string{"*.log", "!keep.log"}, "other.log", false, Ignore},
+		{"last match wins", []string{"!keep.log", "*.log"}, "keep.log", false, Ignore},
+		{"trailing slash matches directory", []string{"logs/"}, "logs", true, Ignore},
+		{"trailing slash skips file", []string{"logs/"}, "logs", false, NoMatch},
+		{"trailing slash nested directory", []string{"logs/"}, "app/logs", true, Ignore},
+		{"trailing ** matches inside", []string{"vendor/**"}, "vendor/a/b.go", false, Ignore},
+		{"trailing ** skips directory itself", []string{"vendor/**"}, "vendor", true, NoMatch},
+		{"inner ** matches zero directories", []string{"a/**/b"}, "a/b", false, Ignore},
+		{"inner ** matches several directories", []string{"a/**/b"}, "a/x/y/b", false, Ignore},
+		{"escaped hash", []string{`\#notes`}, "#notes", false, Ignore},
+		{"escaped bang is literal", []string{`\!important`}, "!important", false, Ignore},
+		{"escaped trailing space kept", []string{`name\ `}, "name ", false, Ignore},
+		{"unescaped trailing space trimmed", []string{"name  "}, "name", false, Ignore},
+		{"comment skipped", []string{"# comment"}, "# comment", false, NoMatch},
+		{"root never matches", []string{"*"}, ".", true, NoMatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := New(tt.patterns).Match(tt.path, tt.isDir); got != tt.want {
+				t.Errorf("Match(%q, %v) with %q = %v, want %v", tt.path, tt.isDir, tt.patterns, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchScoped(t *testing.T) {
+	m := &Matcher{}
+	m.Add("sub", []string{"*.tmp", "/top.txt"})
+
+	tests := []struct {
+		path string
+		want Result
+	}{
+		{"sub/x.tmp", Ignore},
+		{"sub/a/x.tmp", Ignore},
+		{"x.tmp", NoMatch},
+		{"sub/top.txt", Ignore},
+		{"sub/a/top.txt", NoMatch},
+		{"sub", NoMatch},
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.path, false); got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		line string
+		want rule
+		ok   bool
+	}{
+		{"", rule{}, false},
+		{"   ", rule{}, false},
+		{"# comment", rule{}, false},
+		{"!", rule{}, false},
+		{"/", rule{}, false},
+		{"*.log", rule{pattern: "*.log", segments: []string{"**", "*.log"}}, true},
+		{"/dist", rule{pattern: "/dist", segments: []string{"dist"}}, true},
+		{"build/*.o", rule{pattern: "build/*.o", segments: []string{"build", "*.o"}}, true},
+		{"logs/", rule{pattern: "logs/", segments: []string{"**", "logs"}, dirOnly: true}, true},
+		{"!keep.log", rule{pattern: "!keep.log", segments: []string{"**", "keep.log"}, negate: true}, true},
+		{"**/**/node_modules", rule{pattern: "**/**/node_modules", segments: []string{"**", "node_modules"}}, true},
+		{"vendor/**", rule{pattern: "vendor/**", segments: []string{"vendor", "**"}}, true},
+		{`\#notes`, rule{pattern: `\#notes`, segments: []string{"**", "#notes"}}, true},
+		{`\!important`, rule{pattern: `\!important`, segments: []string{"**", "!important"}}, true},
+		{"name\r", rule{pattern: "name", segments: []string{"**", "name"}}, true},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseRule(tt.line, "")
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseRule(%q) = %+v, %v, want %+v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package scanner
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"hash/fnv"
+	"sort"
+	"strings"
+)
+
+// DefaultHashAlgorithm is used when Options.HashAlgorithm is empty
+const DefaultHashAlgorithm = "sha256"
+
+// hashers maps the algorithm names accepted by --hash to their constructors.
+// md5 and sha1 are here to match vendor-published checksums, crc32 and
+// fnv64 for speed on trusted local copies; none is meant to resist
+// tampering except the sha2 family.
+var hashers = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+	"sha1":   sha1.New,
+	"md5":    md5.New,
+	"crc32":  func() hash.Hash { return crc32.NewIEEE() },
+	"fnv64":  func() hash.Hash { return fnv.New64a() },
+}
+
+// HashAlgorithms returns the names of all supported hash algorithms, sorted
+func HashAlgorithms() []string {
+	names := make([]string, 0, len(hashers))
+	for name := range hashers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupHasher returns the constructor registered for name
+func lookupHasher(name string) (func() hash.Hash, error) {
+	newHash, ok := hashers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown hash algorithm %q (supported: %s)",
+			name, strings.Join(HashAlgorithms(), ", "))
+	}
+	return newHash, nil
+}
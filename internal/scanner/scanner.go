@@ -1,86 +1,476 @@
 package scanner
 
 import (
-	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
+	"hash"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
+	"sync"
 
 	"folder-diff-v2/internal/compare"
+	"folder-diff-v2/internal/ignore"
 )
 
+// Options controls which entries a Scanner visits and what it collects
+// for each of them.
+type Options struct {
+	// Mode is the comparison the scan feeds. It decides which metadata is
+	// worth collecting: only HashMode needs every file's contents to be
+	// read. SmartMode hashes lazily through HashFile instead.
+	Mode compare.ComparisonMode
+
+	// ExcludePatterns are gitignore-style patterns matched against each
+	// entry's path relative to the scanned root.
+	ExcludePatterns []string
+
+	// IncludePatterns, when not empty, restrict the scan to files matching
+	// one of them (or inside a directory matching one of them). They use
+	// the same syntax as ExcludePatterns; exclusion always wins.
+	IncludePatterns []string
+
+	// Jobs is the number of files hashed at once, shared by all scans
+	// running on the same Scanner. A value below 1 defaults to GOMAXPROCS.
+	Jobs int
+
+	// HashAlgorithm names the content hash, one of HashAlgorithms().
+	// Empty selects DefaultHashAlgorithm.
+	HashAlgorithm string
+
+	// Strict makes the scan fail on the first unreadable entry. By default
+	// such entries are returned with compare.Error status and the scan
+	// goes on.
+	Strict bool
+
+	// Symlinks decides how symbolic links are scanned. Empty selects
+	// SymlinksCompareTarget.
+	Symlinks SymlinkPolicy
+
+	// RespectGitignore loads .gitignore files found inside the scanned
+	// tree and skips .git entries, as git does. IgnoreFileName files are
+	// honored regardless.
+	RespectGitignore bool
+}
+
+const (
+	// GitignoreFileName is loaded from every directory when
+	// Options.RespectGitignore is set
+	GitignoreFileName = ".gitignore"
+
+	// IgnoreFileName is folder-diff's own ignore file, always loaded from
+	// every directory
+	IgnoreFileName = ".folderdiffignore"
+
+	// gitDirName is skipped when Options.RespectGitignore is set
+	gitDirName = ".git"
+)
+
+// collectHash reports whether file contents must be hashed during the scan.
+func (o Options) collectHash() bool {
+	return o.Mode == compare.HashMode
+}
+
 type Scanner struct {
-	excludePatterns []string
+	opts      Options
+	newHash   func() hash.Hash
+	excludes  *ignore.Matcher
+	includes  *ignore.Matcher
+	hashSlots chan struct{} // Bounds concurrent HashFile calls to Jobs
 }
 
-func NewScanner(excludePatterns []string) *Scanner {
+// NewScanner creates a scanner, failing if the hash algorithm or the
+// symlink policy is unknown.
+func NewScanner(opts Options) (*Scanner, error) {
+	if opts.Jobs < 1 {
+		opts.Jobs = runtime.GOMAXPROCS(0)
+	}
+	if opts.HashAlgorithm == "" {
+		opts.HashAlgorithm = DefaultHashAlgorithm
+	}
+	if opts.Symlinks == "" {
+		opts.Symlinks = SymlinksCompareTarget
+	}
+	if err := opts.Symlinks.validate(); err != nil {
+		return nil, err
+	}
+
+	newHash, err := lookupHasher(opts.HashAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Scanner{
-		excludePatterns: excludePatterns,
+		opts:      opts,
+		newHash:   newHash,
+		excludes:  ignore.New(opts.ExcludePatterns),
+		includes:  ignore.New(opts.IncludePatterns),
+		hashSlots: make(chan struct{}, opts.Jobs),
+	}, nil
+}
+
+// HashAlgorithm returns the name of the algorithm used by HashFile
+func (s *Scanner) HashAlgorithm() string {
+	return s.opts.HashAlgorithm
+}
+
+// shouldExclude reports whether the entry at relPath, relative to the
+// scanned root, is excluded. Exclude patterns from the options take
+// precedence over the ignore files found in the tree.
+func (s *Scanner) shouldExclude(treeRules *ignore.Matcher, relPath string, isDir bool) bool {
+	relPath = filepath.ToSlash(relPath)
+	if result := s.excludes.Match(relPath, isDir); result != ignore.NoMatch {
+		return result == ignore.Ignore
+	}
+	// Git never tracks its own repository directory, nor the .git file
+	// of a worktree or submodule
+	if s.opts.RespectGitignore && path.Base(relPath) == gitDirName {
+		return true
 	}
+	return treeRules.Ignored(relPath, isDir)
 }
 
-func (s *Scanner) shouldExclude(path string) bool {
-	for _, pattern := range s.excludePatterns {
-		if matched, _ := filepath.Match(pattern, filepath.Base(path)); matched {
+// shouldInclude reports whether the file at relPath passes the include
+// patterns. Directories are not filtered here: they are always descended
+// and dropped afterwards if nothing inside them was included.
+func (s *Scanner) shouldInclude(relPath string) bool {
+	if s.includes.Len() == 0 {
+		return true
+	}
+
+	// A file is included by a pattern matching it or any of its parent
+	// directories, so "src/" selects everything below src.
+	relPath = filepath.ToSlash(relPath)
+	isDir := false
+	for relPath != "." {
+		switch s.includes.Match(relPath, isDir) {
+		case ignore.Ignore:
 			return true
+		case ignore.Include:
+			// A negated include pattern ("!*_test.go") deselects
+			return false
 		}
+		relPath = path.Dir(relPath)
+		isDir = true
 	}
 	return false
 }
 
-func (s *Scanner) calculateHash(path string) (string, error) {
+// pruneEmptyDirs drops directories that ended up with no included file
+// below them. It only applies when include patterns are in use.
+func (s *Scanner) pruneEmptyDirs(files []*compare.FileInfo) []*compare.FileInfo {
+	if s.includes.Len() == 0 {
+		return files
+	}
+
+	used := map[string]bool{".": true}
+	for _, file := range files {
+		if file.IsDir {
+			continue
+		}
+		for dir := filepath.Dir(file.RelPath); !used[dir]; dir = filepath.Dir(dir) {
+			used[dir] = true
+		}
+	}
+
+	kept := files[:0]
+	for _, file := range files {
+		if !file.IsDir || used[file.RelPath] {
+			kept = append(kept, file)
+		}
+	}
+	return kept
+}
+
+// loadIgnoreFiles adds the rules of the ignore files in dir to treeRules,
+// scoped to dir. Rules are appended as the walk descends, so deeper files
+// take precedence over their ancestors, and .folderdiffignore over a
+// .gitignore in the same directory.
+func (s *Scanner) loadIgnoreFiles(treeRules *ignore.Matcher, dir, relDir string) error {
+	names := []string{IgnoreFileName}
+	if s.opts.RespectGitignore {
+		names = []string{GitignoreFileName, IgnoreFileName}
+	}
+
+	for _, name := range names {
+		patterns, err := ignore.ReadPatterns(filepath.Join(dir, name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		treeRules.Add(filepath.ToSlash(relDir), patterns)
+	}
+	return nil
+}
+
+// HashFile returns the hex-encoded content hash of the file at path. It is
+// safe for concurrent use and suits compare.Options.Hash. At most Jobs
+// files are hashed at once, whatever the number of callers.
+func (s *Scanner) HashFile(path string) (string, error) {
+	s.hashSlots <- struct{}{}
+	defer func() { <-s.hashSlots }()
+
 	file, err := os.Open(path)
 	if err != nil {
 		return "", err
 	}
 	defer file.Close()
 
-	hash := sha256.New()
-	if _, err := io.Copy(hash, file); err != nil {
+	h := s.newHash()
+	if _, err := io.Copy(h, file); err != nil {
 		return "", err
 	}
 
-	return hex.EncodeToString(hash.Sum(nil)), nil
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
+// ScanDirectory walks root and returns its entries in walk order. When the
+// options require content hashes, files are handed to a bounded pool of
+// hashing workers as they are discovered, so the walk and the hashing
+// overlap; the returned order does not depend on which worker finishes
+// first; concurrent scans on the same Scanner share the Jobs limit.
+// Otherwise the scan is a plain directory walk and Hash stays empty.
+// Ignore files only apply within the root they were found in.
 func (s *Scanner) ScanDirectory(root string) ([]*compare.FileInfo, error) {
-	var files []*compare.FileInfo
+	w := &walker{
+		scanner:   s,
+		root:      root,
+		treeRules: &ignore.Matcher{},
+		active:    make(map[string]bool),
+	}
+	if s.opts.collectHash() {
+		w.pool = newHashPool(s.opts.Jobs, s.opts.HashAlgorithm, s.opts.Strict, s.HashFile)
+	}
 
-	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+	err := w.visit(root, ".")
+
+	if w.pool != nil {
+		if hashErr := w.pool.wait(); err == nil {
+			err = hashErr
 		}
+	}
 
-		if s.shouldExclude(path) {
-			if info.IsDir() {
-				return filepath.SkipDir
-			}
+	return s.pruneEmptyDirs(w.files), err
+}
+
+// walker holds the state of a single ScanDirectory call, so that concurrent
+// scans sharing a Scanner do not interfere
+type walker struct {
+	scanner   *Scanner
+	root      string
+	files     []*compare.FileInfo
+	treeRules *ignore.Matcher
+	pool      *hashPool
+
+	// active holds the resolved paths of the directories currently being
+	// walked, to detect symlink cycles when following links
+	active map[string]bool
+}
+
+// visit adds the entry at path and, for directories, everything below it.
+// Entries are visited in lexical order, like filepath.Walk. Errors are
+// only returned in strict mode or for the root itself; otherwise they are
+// recorded on the entry and the walk goes on.
+func (w *walker) visit(path, relPath string) error {
+	s := w.scanner
+
+	// The root is always resolved; links are only special below it
+	stat := os.Lstat
+	if relPath == "." {
+		stat = os.Stat
+	}
+	info, err := stat(path)
+	if err != nil {
+		return w.fail(path, relPath, false, err)
+	}
+
+	var linkTarget string
+	if info.Mode()&os.ModeSymlink != 0 {
+		if s.opts.Symlinks == SymlinksSkip {
 			return nil
 		}
+		if linkTarget, err = os.Readlink(path); err != nil {
+			return w.fail(path, relPath, false, err)
+		}
+		if s.opts.Symlinks == SymlinksFollow {
+			if info, err = os.Stat(path); err != nil {
+				return w.fail(path, relPath, false, err)
+			}
+		}
+	}
+
+	if relPath != "." && s.shouldExclude(w.treeRules, relPath, info.IsDir()) {
+		return nil
+	}
+	if !info.IsDir() && !s.shouldInclude(relPath) {
+		return nil
+	}
+
+	fileInfo := &compare.FileInfo{
+		Path:       path,
+		RelPath:    relPath,
+		IsDir:      info.IsDir(),
+		ModTime:    info.ModTime(),
+		Mode:       info.Mode(),
+		LinkTarget: linkTarget,
+		IsSymlink:  linkTarget != "" && s.opts.Symlinks == SymlinksCompareTarget,
+	}
+	if uid, gid, ok := FileOwner(info); ok {
+		fileInfo.UID = uid
+		fileInfo.GID = gid
+	}
+	w.files = append(w.files, fileInfo)
+
+	if !info.IsDir() {
+		fileInfo.Size = info.Size()
+		if w.pool != nil && !fileInfo.IsSymlink {
+			return w.pool.submit(fileInfo)
+		}
+		return nil
+	}
 
-		relPath, err := filepath.Rel(root, path)
+	return w.visitDir(fileInfo, relPath)
+}
+
+// visitDir loads the ignore files of a directory and visits its entries
+func (w *walker) visitDir(dir *compare.FileInfo, relPath string) error {
+	s := w.scanner
+
+	// Following links can lead back into a directory being walked
+	if s.opts.Symlinks == SymlinksFollow {
+		realPath, err := filepath.EvalSymlinks(dir.Path)
 		if err != nil {
+			return w.failEntry(dir, err)
+		}
+		if w.active[realPath] {
+			return w.failEntry(dir, fmt.Errorf("symlink cycle: %s leads back to %s", dir.Path, realPath))
+		}
+		w.active[realPath] = true
+		defer delete(w.active, realPath)
+	}
+
+	if err := s.loadIgnoreFiles(w.treeRules, dir.Path, relPath); err != nil {
+		if err := w.failEntry(dir, err); err != nil {
+			return err
+		}
+	}
+
+	entries, err := os.ReadDir(dir.Path)
+	if err != nil {
+		if relPath == "." {
 			return err
 		}
+		return w.failEntry(dir, err)
+	}
 
-		fileInfo := &compare.FileInfo{
-			Path:    path,
-			RelPath: relPath,
-			IsDir:   info.IsDir(),
+	for _, entry := range entries {
+		childPath := filepath.Join(dir.Path, entry.Name())
+		childRel := filepath.Join(relPath, entry.Name())
+		if err := w.visit(childPath, childRel); err != nil {
+			return err
 		}
+	}
+	return nil
+}
+
+// fail handles an entry that could not be stat'ed or resolved. Unless the
+// scan is strict, it is kept as a compare.Error entry if not excluded.
+func (w *walker) fail(path, relPath string, isDir bool, err error) error {
+	s := w.scanner
+	if s.opts.Strict || relPath == "." {
+		return err
+	}
+	if s.shouldExclude(w.treeRules, relPath, isDir) || (!isDir && !s.shouldInclude(relPath)) {
+		return nil
+	}
+
+	fileInfo := &compare.FileInfo{
+		Path:    path,
+		RelPath: relPath,
+		IsDir:   isDir,
+	}
+	fileInfo.SetError(err)
+	w.files = append(w.files, fileInfo)
+	return nil
+}
 
-		if !info.IsDir() {
-			hash, err := s.calculateHash(path)
-			if err != nil {
-				return err
+// failEntry records an error on an entry already added to the scan, or
+// returns it in strict mode
+func (w *walker) failEntry(file *compare.FileInfo, err error) error {
+	if w.scanner.opts.Strict {
+		return err
+	}
+	file.SetError(err)
+	return nil
+}
+
+// hashPool fills in FileInfo.Hash using a fixed number of workers. In
+// strict mode the first hashing error stops further submissions and is
+// reported by wait; otherwise failed files are marked compare.Error.
+type hashPool struct {
+	work   chan *compare.FileInfo
+	done   chan struct{}
+	wg     sync.WaitGroup
+	once   sync.Once
+	err    error
+	strict bool
+}
+
+func newHashPool(workers int, algorithm string, strict bool, hash func(path string) (string, error)) *hashPool {
+	p := &hashPool{
+		work:   make(chan *compare.FileInfo, workers*2),
+		done:   make(chan struct{}),
+		strict: strict,
+	}
+
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for file := range p.work {
+				sum, err := hash(file.Path)
+				if err != nil {
+					if p.strict {
+						p.fail(err)
+					} else {
+						file.SetError(err)
+					}
+					continue
+				}
+				file.Hash = sum
+				file.HashAlgorithm = algorithm
 			}
-			fileInfo.Hash = hash
-		}
+		}()
+	}
+
+	return p
+}
 
-		files = append(files, fileInfo)
+// submit queues a file for hashing, blocking while all workers are busy.
+func (p *hashPool) submit(file *compare.FileInfo) error {
+	select {
+	case <-p.done:
+		return p.err
+	case p.work <- file:
 		return nil
+	}
+}
+
+func (p *hashPool) fail(err error) {
+	p.once.Do(func() {
+		p.err = err
+		close(p.done)
 	})
+}
 
-	return files, err
+// wait closes the queue, waits for in-flight hashes and returns the first
+// hashing error, if any.
+func (p *hashPool) wait() error {
+	close(p.work)
+	p.wg.Wait()
+	return p.err
 }
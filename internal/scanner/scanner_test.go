@@ -0,0 +1,123 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"folder-diff-v2/internal/compare"
+)
+
+// writeTree creates the files in root, keyed by slash-separated relative
+// path. Paths ending in "/" are created as empty directories.
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for relPath, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(relPath))
+		if relPath[len(relPath)-1] == '/' {
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// scan scans root with opts, failing the test on error
+func scan(t *testing.T, opts Options, root string) []*compare.FileInfo {
+	t.Helper()
+	s, err := NewScanner(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := s.ScanDirectory(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// relPaths returns the slash-separated relative paths of files, in scan
+// order and without the root
+func relPaths(files []*compare.FileInfo) []string {
+	var paths []string
+	for _, file := range files {
+		if file.RelPath != "." {
+			paths = append(paths, filepath.ToSlash(file.RelPath))
+		}
+	}
+	return paths
+}
+
+func TestScanPatterns(t *testing.T) {
+	tree := map[string]string{
+		"main.go":          "package main",
+		"README.md":        "readme",
+		"docs/guide.md":    "guide",
+		"src/a.go":         "package src",
+		"src/a_test.go":    "package src",
+		"src/gen/b.go":     "package gen",
+		"vendor/dep/c.go":  "package dep",
+		"empty/":           "",
+		"assets/logo.png":  "png",
+		"assets/style.css": "css",
+	}
+
+	tests := []struct {
+		name     string
+		includes []string
+		excludes []string
+		want     []string
+	}{
+		{
+			name: "everything",
+			want: []string{"README.md", "assets", "assets/logo.png", "assets/style.css", "docs", "docs/guide.md",
+				"empty", "main.go", "src", "src/a.go", "src/a_test.go", "src/gen", "src/gen/b.go", "vendor", "vendor/dep", "vendor/dep/c.go"},
+		},
+		{
+			name:     "include by extension prunes other directories",
+			includes: []string{"*.go"},
+			want:     []string{"main.go", "src", "src/a.go", "src/a_test.go", "src/gen", "src/gen/b.go", "vendor", "vendor/dep", "vendor/dep/c.go"},
+		},
+		{
+			name:     "negated include deselects",
+			includes: []string{"*.go", "!*_test.go"},
+			want:     []string{"main.go", "src", "src/a.go", "src/gen", "src/gen/b.go", "vendor", "vendor/dep", "vendor/dep/c.go"},
+		},
+		{
+			name:     "include directory selects everything below it",
+			includes: []string{"src/"},
+			want:     []string{"src", "src/a.go", "src/a_test.go", "src/gen", "src/gen/b.go"},
+		},
+		{
+			name:     "exclusion wins over inclusion",
+			includes: []string{"*.go"},
+			excludes: []string{"vendor/", "gen"},
+			want:     []string{"main.go", "src", "src/a.go", "src/a_test.go"},
+		},
+		{
+			name:     "exclude only",
+			excludes: []string{"*.md", "assets/*.png"},
+			want: []string{"assets", "assets/style.css", "docs", "empty", "main.go", "src", "src/a.go", "src/a_test.go",
+				"src/gen", "src/gen/b.go", "vendor", "vendor/dep", "vendor/dep/c.go"},
+		},
+	}
+
+	root := t.TempDir()
+	writeTree(t, root, tree)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := scan(t, Options{Mode: compare.FilenameMode, IncludePatterns: tt.includes, ExcludePatterns: tt.excludes}, root)
+			if got := relPaths(files); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("scanned %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package scanner
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SymlinkPolicy decides how a scan treats symbolic links
+type SymlinkPolicy string
+
+const (
+	// SymlinksCompareTarget keeps links as entries of their own, compared
+	// by the path they point to. Link targets are never read.
+	SymlinksCompareTarget SymlinkPolicy = "compare-target"
+
+	// SymlinksFollow scans what links point to as if it were in their
+	// place, descending into linked directories. Links leading back into
+	// a directory being walked are reported as errors.
+	SymlinksFollow SymlinkPolicy = "follow"
+
+	// SymlinksSkip leaves links out of the scan
+	SymlinksSkip SymlinkPolicy = "skip"
+)
+
+// SymlinkPolicies lists the accepted policies, default first
+var SymlinkPolicies = []SymlinkPolicy{SymlinksCompareTarget, SymlinksFollow, SymlinksSkip}
+
+func (p SymlinkPolicy) validate() error {
+	for _, policy := range SymlinkPolicies {
+		if p == policy {
+			return nil
+		}
+	}
+
+	names := make([]string, len(SymlinkPolicies))
+	for i, policy := range SymlinkPolicies {
+		names[i] = string(policy)
+	}
+	return fmt.Errorf("unknown symlink policy %q (supported: %s)", p, strings.Join(names, ", "))
+}
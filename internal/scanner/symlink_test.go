@@ -0,0 +1,142 @@
+package scanner
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"folder-diff-v2/internal/compare"
+)
+
+// symlink creates a link at root/relPath pointing to target
+func symlink(t *testing.T, root, target, relPath string) {
+	t.Helper()
+	if err := os.Symlink(target, filepath.Join(root, filepath.FromSlash(relPath))); err != nil {
+		t.Skipf("cannot create symlinks: %v", err)
+	}
+}
+
+// byRelPath indexes files by slash-separated relative path
+func byRelPath(files []*compare.FileInfo) map[string]*compare.FileInfo {
+	index := make(map[string]*compare.FileInfo)
+	for _, file := range files {
+		index[filepath.ToSlash(file.RelPath)] = file
+	}
+	return index
+}
+
+// linkTree creates a tree with links to a file, a directory, nothing, and
+// back to the root
+func linkTree(t *testing.T) string {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"file.txt":      "content",
+		"dir/inner.txt": "inner",
+		"loop/":         "",
+	})
+	symlink(t, root, "file.txt", "link-file")
+	symlink(t, root, "dir", "link-dir")
+	symlink(t, root, "missing", "dangling")
+	symlink(t, root, "..", "loop/back")
+	return root
+}
+
+func TestSymlinkPolicies(t *testing.T) {
+	tests := []struct {
+		policy SymlinkPolicy
+		want   []string
+	}{
+		{SymlinksCompareTarget, []string{"dangling", "dir", "dir/inner.txt", "file.txt", "link-dir", "link-file", "loop", "loop/back"}},
+		{SymlinksFollow, []string{"dangling", "dir", "dir/inner.txt", "file.txt", "link-dir", "link-dir/inner.txt", "link-file", "loop", "loop/back"}},
+		{SymlinksSkip, []string{"dir", "dir/inner.txt", "file.txt", "loop"}},
+	}
+
+	root := linkTree(t)
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			files := scan(t, Options{Mode: compare.HashMode, Symlinks: tt.policy}, root)
+			if got := relPaths(files); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("scanned %q, want %q", got, tt.want)
+			}
+
+			index := byRelPath(files)
+			switch tt.policy {
+			case SymlinksCompareTarget:
+				for relPath, target := range map[string]string{"link-file": "file.txt", "link-dir": "dir", "dangling": "missing", "loop/back": ".."} {
+					link := index[relPath]
+					if !link.IsSymlink || link.IsDir || link.LinkTarget != target || link.Hash != "" || link.Status == compare.Error {
+						t.Errorf("%s: %+v, want an unfollowed link to %q", relPath, link, target)
+					}
+				}
+
+			case SymlinksFollow:
+				link := index["link-file"]
+				if link.IsSymlink || link.LinkTarget != "file.txt" || link.Hash != index["file.txt"].Hash || link.Size != int64(len("content")) {
+					t.Errorf("link-file: %+v, want the followed content of file.txt", link)
+				}
+				if dir := index["link-dir"]; !dir.IsDir || dir.IsSymlink || dir.LinkTarget != "dir" {
+					t.Errorf("link-dir: %+v, want a followed directory", dir)
+				}
+				if dangling := index["dangling"]; dangling.Status != compare.Error {
+					t.Errorf("dangling: status %s, want %s", dangling.Status, compare.Error)
+				}
+			}
+		})
+	}
+}
+
+func TestSymlinkCycle(t *testing.T) {
+	root := linkTree(t)
+
+	files := scan(t, Options{Mode: compare.FilenameMode, Symlinks: SymlinksFollow}, root)
+	back := byRelPath(files)["loop/back"]
+	if back.Status != compare.Error || !strings.Contains(back.ErrorMessage, "symlink cycle") {
+		t.Errorf("loop/back: status %s, message %q; want a symlink cycle error", back.Status, back.ErrorMessage)
+	}
+
+	s, err := NewScanner(Options{Mode: compare.FilenameMode, Symlinks: SymlinksFollow, Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ScanDirectory(root); err == nil {
+		t.Error("strict scan through a symlink cycle succeeded")
+	}
+}
+
+func TestSymlinkPolicyValidation(t *testing.T) {
+	_, err := NewScanner(Options{Symlinks: "resolve"})
+	if err == nil {
+		t.Fatal("NewScanner accepted an unknown symlink policy")
+	}
+	for _, policy := range SymlinkPolicies {
+		if !strings.Contains(err.Error(), string(policy)) {
+			t.Errorf("error %q does not list policy %q", err, policy)
+		}
+	}
+}
+
+func TestCompareSymlinkTargets(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	for _, root := range []string{source, target} {
+		writeTree(t, root, map[string]string{"a.txt": "a", "b.txt": "b"})
+		symlink(t, root, "a.txt", "same")
+	}
+	symlink(t, source, "a.txt", "moved")
+	symlink(t, target, "b.txt", "moved")
+
+	opts := Options{Mode: compare.HashMode}
+	sourceFiles, targetFiles := scan(t, opts, source), scan(t, opts, target)
+	if _, err := compare.NewComparator(compare.Options{Mode: compare.HashMode}).Compare(sourceFiles, targetFiles); err != nil {
+		t.Fatal(err)
+	}
+
+	index := byRelPath(sourceFiles)
+	if got := index["same"].Status; got != compare.Identical {
+		t.Errorf("same: status %s, want %s", got, compare.Identical)
+	}
+	if got := index["moved"].Status; got != compare.Modified {
+		t.Errorf("moved: status %s, want %s", got, compare.Modified)
+	}
+}
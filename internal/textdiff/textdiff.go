@@ -0,0 +1,213 @@
+// Package textdiff computes line diffs between two texts and groups them
+// into hunks, as shown by the TUI's diff view.
+package textdiff
+
+import "strings"
+
+// Kind tells whether a diff line is shared, removed or added
+type Kind int
+
+const (
+	Equal  Kind = iota // Present in both texts
+	Delete             // Only in the old text
+	Insert             // Only in the new text
+)
+
+// Line is one line of a diff. OldLine and NewLine are 1-based line numbers
+// in the old and new text, or 0 where the line does not exist.
+type Line struct {
+	Kind    Kind
+	Text    string
+	OldLine int
+	NewLine int
+}
+
+// Hunk is a run of changed lines with their surrounding context, given as
+// the half-open range [Start, End) of indexes into the diff lines
+type Hunk struct {
+	Start int
+	End   int
+}
+
+// maxEdits bounds the edit distance searched for a minimal diff. Texts
+// further apart are diffed as one replacement of their differing middle,
+// which keeps time and memory in check for unrelated files.
+const maxEdits = 1024
+
+// SplitLines splits data into lines without their line endings. A final
+// line ending does not start another, empty line.
+func SplitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	text := strings.TrimSuffix(string(data), "\n")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+	return lines
+}
+
+// Diff returns a minimal line diff turning old into new, using Myers'
+// algorithm
+func Diff(old, new []string) []Line {
+	// Common prefix and suffix need no search
+	prefix := 0
+	for prefix < len(old) && prefix < len(new) && old[prefix] == new[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(old)-prefix && suffix < len(new)-prefix &&
+		old[len(old)-1-suffix] == new[len(new)-1-suffix] {
+		suffix++
+	}
+
+	var lines []Line
+	for i := 0; i < prefix; i++ {
+		lines = append(lines, Line{Kind: Equal, Text: old[i], OldLine: i + 1, NewLine: i + 1})
+	}
+
+	middle := myers(old[prefix:len(old)-suffix], new[prefix:len(new)-suffix])
+	for _, line := range middle {
+		if line.OldLine > 0 {
+			line.OldLine += prefix
+		}
+		if line.NewLine > 0 {
+			line.NewLine += prefix
+		}
+		lines = append(lines, line)
+	}
+
+	for i := suffix; i > 0; i-- {
+		oldIndex, newIndex := len(old)-i, len(new)-i
+		lines = append(lines, Line{Kind: Equal, Text: old[oldIndex], OldLine: oldIndex + 1, NewLine: newIndex + 1})
+	}
+	return lines
+}
+
+// myers diffs old and new by searching the shortest edit script, keeping
+// the furthest reaching x of every diagonal k after each edit count d
+func myers(old, new []string) []Line {
+	n, m := len(old), len(new)
+	if n == 0 && m == 0 {
+		return nil
+	}
+
+	// trace[d][k+d] is the furthest x reached on diagonal k with d edits
+	var trace [][]int
+	furthest := func(d, k int) int {
+		if d < 0 {
+			return 0
+		}
+		return trace[d][k+d]
+	}
+
+	for d := 0; d <= n+m; d++ {
+		if d > maxEdits {
+			return replace(old, new)
+		}
+		current := make([]int, 2*d+1)
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && furthest(d-1, k-1) < furthest(d-1, k+1)) {
+				x = furthest(d-1, k+1) // Step down: insert
+			} else {
+				x = furthest(d-1, k-1) + 1 // Step right: delete
+			}
+			y := x - k
+			for x < n && y < m && old[x] == new[y] {
+				x++
+				y++
+			}
+			current[k+d] = x
+			if x >= n && y >= m {
+				trace = append(trace, current)
+				return backtrack(old, new, trace)
+			}
+		}
+		trace = append(trace, current)
+	}
+	return replace(old, new)
+}
+
+// backtrack walks the trace from the end back to the start, collecting
+// the edit script in reverse
+func backtrack(old, new []string, trace [][]int) []Line {
+	var lines []Line
+	x, y := len(old), len(new)
+
+	for d := len(trace) - 1; d > 0; d-- {
+		previous := trace[d-1]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && previous[k-1+d-1] < previous[k+1+d-1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := previous[prevK+d-1]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			lines = append(lines, Line{Kind: Equal, Text: old[x-1], OldLine: x, NewLine: y})
+			x--
+			y--
+		}
+		if x == prevX {
+			lines = append(lines, Line{Kind: Insert, Text: new[y-1], NewLine: y})
+			y--
+		} else {
+			lines = append(lines, Line{Kind: Delete, Text: old[x-1], OldLine: x})
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		lines = append(lines, Line{Kind: Equal, Text: old[x-1], OldLine: x, NewLine: y})
+		x--
+		y--
+	}
+
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return lines
+}
+
+// replace diffs old and new as all of old deleted and all of new inserted
+func replace(old, new []string) []Line {
+	lines := make([]Line, 0, len(old)+len(new))
+	for i, text := range old {
+		lines = append(lines, Line{Kind: Delete, Text: text, OldLine: i + 1})
+	}
+	for i, text := range new {
+		lines = append(lines, Line{Kind: Insert, Text: text, NewLine: i + 1})
+	}
+	return lines
+}
+
+// Hunks groups the changed lines of a diff into hunks with up to context
+// unchanged lines around them. Changes separated by no more than twice the
+// context share a hunk.
+func Hunks(lines []Line, context int) []Hunk {
+	var hunks []Hunk
+	for i := 0; i < len(lines); i++ {
+		if lines[i].Kind == Equal {
+			continue
+		}
+
+		start := max(i-context, 0)
+		if n := len(hunks); n > 0 && start <= hunks[n-1].End {
+			start = hunks[n-1].Start
+			hunks = hunks[:n-1]
+		}
+
+		// Extend over this change to the next unchanged line
+		for i < len(lines) && lines[i].Kind != Equal {
+			i++
+		}
+		end := min(i+context, len(lines))
+		hunks = append(hunks, Hunk{Start: start, End: end})
+	}
+	return hunks
+}
@@ -0,0 +1,175 @@
+package textdiff
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitLines(t *testing.T) {
+	tests := []struct {
+		data string
+		want []string
+	}{
+		{"", nil},
+		{"\n", []string{""}},
+		{"a", []string{"a"}},
+		{"a\n", []string{"a"}},
+		{"a\nb", []string{"a", "b"}},
+		{"a\r\nb\r\n", []string{"a", "b"}},
+		{"a\n\nb\n", []string{"a", "", "b"}},
+	}
+	for _, tt := range tests {
+		if got := SplitLines([]byte(tt.data)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SplitLines(%q) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
+
+// render writes a diff in a compact form: " a" for shared lines, "-a" for
+// deleted and "+a" for inserted ones
+func render(lines []Line) string {
+	var parts []string
+	for _, line := range lines {
+		prefix := map[Kind]string{Equal: " ", Delete: "-", Insert: "+"}[line.Kind]
+		parts = append(parts, prefix+line.Text)
+	}
+	return strings.Join(parts, ",")
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{"both empty", "", "", ""},
+		{"identical", "a b c", "a b c", " a, b, c"},
+		{"all inserted", "", "a b", "+a,+b"},
+		{"all deleted", "a b", "", "-a,-b"},
+		{"changed middle", "a b c", "a x c", " a,-b,+x, c"},
+		{"inserted line", "a c", "a b c", " a,+b, c"},
+		{"deleted line", "a b c", "a c", " a,-b, c"},
+		{"moved line", "a b c d", "b c d a", "-a, b, c, d,+a"},
+	}
+
+	split := func(s string) []string {
+		if s == "" {
+			return nil
+		}
+		return strings.Split(s, " ")
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := render(Diff(split(tt.old), split(tt.new))); got != tt.want {
+				t.Errorf("Diff(%q, %q) = %q, want %q", tt.old, tt.new, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffMinimal(t *testing.T) {
+	// The classic example from Myers' paper has an edit distance of 5
+	old := strings.Split("A B C A B B A", " ")
+	new := strings.Split("C B A B A C", " ")
+	edits := 0
+	for _, line := range Diff(old, new) {
+		if line.Kind != Equal {
+			edits++
+		}
+	}
+	if edits != 5 {
+		t.Errorf("Diff has %d edits, want 5", edits)
+	}
+}
+
+// checkLines verifies that the diff reproduces both texts and numbers
+// their lines consecutively
+func checkLines(t *testing.T, old, new []string, lines []Line) {
+	t.Helper()
+	var gotOld, gotNew []string
+	for _, line := range lines {
+		if line.Kind != Insert {
+			gotOld = append(gotOld, line.Text)
+			if line.OldLine != len(gotOld) {
+				t.Fatalf("line %q has old number %d, want %d", line.Text, line.OldLine, len(gotOld))
+			}
+		} else if line.OldLine != 0 {
+			t.Fatalf("inserted line %q has old number %d", line.Text, line.OldLine)
+		}
+		if line.Kind != Delete {
+			gotNew = append(gotNew, line.Text)
+			if line.NewLine != len(gotNew) {
+				t.Fatalf("line %q has new number %d, want %d", line.Text, line.NewLine, len(gotNew))
+			}
+		} else if line.NewLine != 0 {
+			t.Fatalf("deleted line %q has new number %d", line.Text, line.NewLine)
+		}
+	}
+	if !reflect.DeepEqual(gotOld, old) || !reflect.DeepEqual(gotNew, new) {
+		t.Fatalf("diff does not reproduce its inputs")
+	}
+}
+
+func TestDiffReproducesInputs(t *testing.T) {
+	var old, new []string
+	for i := 0; i < 200; i++ {
+		if i%7 != 0 {
+			old = append(old, fmt.Sprint("line ", i))
+		}
+		if i%5 != 0 {
+			new = append(new, fmt.Sprint("line ", i))
+		}
+	}
+	checkLines(t, old, new, Diff(old, new))
+}
+
+func TestDiffBeyondMaxEdits(t *testing.T) {
+	var old, new []string
+	for i := 0; i < maxEdits; i++ {
+		old = append(old, fmt.Sprint("old ", i))
+		new = append(new, fmt.Sprint("new ", i))
+	}
+	old = append([]string{"head"}, append(old, "tail")...)
+	new = append([]string{"head"}, append(new, "tail")...)
+
+	lines := Diff(old, new)
+	checkLines(t, old, new, lines)
+	// The middle is replaced as a whole: all deletions, then all insertions
+	if lines[1].Kind != Delete || lines[maxEdits].Kind != Delete || lines[maxEdits+1].Kind != Insert {
+		t.Errorf("middle was not diffed as one replacement")
+	}
+}
+
+func TestHunks(t *testing.T) {
+	// kinds builds a diff from a pattern of '=', '-' and '+'
+	kinds := func(pattern string) []Line {
+		lines := make([]Line, len(pattern))
+		for i, c := range pattern {
+			lines[i].Kind = map[rune]Kind{'=': Equal, '-': Delete, '+': Insert}[c]
+		}
+		return lines
+	}
+
+	tests := []struct {
+		name    string
+		pattern string
+		context int
+		want    []Hunk
+	}{
+		{"no changes", "=====", 3, nil},
+		{"single change with context", "=====-+=====", 3, []Hunk{{2, 10}}},
+		{"context clipped at the ends", "=-=", 3, []Hunk{{0, 3}}},
+		{"close changes merge", "-======-", 3, []Hunk{{0, 8}}},
+		{"distant changes split", "-=======-", 3, []Hunk{{0, 4}, {5, 9}}},
+		{"no context", "=-==+=", 0, []Hunk{{1, 2}, {4, 5}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Hunks(kinds(tt.pattern), tt.context); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Hunks(%q, %d) = %v, want %v", tt.pattern, tt.context, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,614 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"folder-diff-v2/internal/compare"
+	"folder-diff-v2/internal/fsops"
+	"folder-diff-v2/internal/scanner"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// Side names one of the two compared trees
+type Side int
+
+const (
+	SourceSide Side = iota
+	TargetSide
+)
+
+// String returns the side's name as shown in dialogs
+func (s Side) String() string {
+	if s == SourceSide {
+		return "source"
+	}
+	return "target"
+}
+
+// other returns the opposite side
+func (s Side) other() Side {
+	if s == SourceSide {
+		return TargetSide
+	}
+	return SourceSide
+}
+
+// file returns the node's entry on side, or nil if it does not exist there
+func (n *SyncNode) file(side Side) *compare.FileInfo {
+	if side == SourceSide {
+		return n.SourceFile
+	}
+	return n.TargetFile
+}
+
+// setFile sets the node's entry on side
+func (n *SyncNode) setFile(side Side, file *compare.FileInfo) {
+	if side == SourceSide {
+		n.SourceFile = file
+	} else {
+		n.TargetFile = file
+	}
+}
+
+// dir returns the root directory of side
+func (l *Layout) dir(side Side) string {
+	if side == SourceSide {
+		return l.sourceDir
+	}
+	return l.targetDir
+}
+
+// confirm asks question in a dialog over the tree and calls action if the
+// user chooses button
+func (l *Layout) confirm(question, button string, action func()) {
+	modal := tview.NewModal().
+		SetText(question).
+		AddButtons([]string{button, "Cancel"}).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			l.closeOverlay()
+			if buttonLabel == button {
+				action()
+			}
+		})
+	l.showOverlay(modal, func(event *tcell.EventKey) *tcell.EventKey {
+		return event
+	})
+}
+
+// notify shows message in the detail line until the selection changes
+func (l *Layout) notify(message string) {
+	l.detailBar.SetText(message)
+}
+
+// CopySelected asks to copy the marked entries, or else the selected one,
+// from side to the other one, with everything below directories. Entries
+// that already exist on the other side are replaced.
+func (l *Layout) CopySelected(from Side) {
+	roots := l.actionNodes()
+	if len(roots) == 0 {
+		return
+	}
+
+	files, replaced := 0, 0
+	var size int64
+	for _, root := range roots {
+		for _, n := range copyable(root, from) {
+			if source := n.file(from); !source.IsDir {
+				files++
+				size += source.Size
+				if n.file(from.other()) != nil {
+					replaced++
+				}
+			}
+		}
+	}
+	if files == 0 && !anyDir(roots, from) {
+		l.notify(fmt.Sprintf("[yellow]Nothing to copy from the %s[-]", from))
+		return
+	}
+
+	question := fmt.Sprintf("Copy %s from the %s to the %s?\n\n%s, %s",
+		describeNodes(roots), from, from.other(), plural(files, "file"), formatSize(size))
+	if replaced > 0 {
+		question += fmt.Sprintf(", replacing %d", replaced)
+	}
+	l.confirm(question, "Copy", func() {
+		l.copyNodes(roots, from)
+	})
+}
+
+// anyDir reports whether any of nodes is a readable directory on side
+func anyDir(nodes []*SyncNode, side Side) bool {
+	for _, n := range nodes {
+		if file := n.file(side); file != nil && file.IsDir && file.ErrorMessage == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// copyable returns node and the nodes below it that can be copied from
+// side, in display order: readable entries on that side, leaving out files
+// that are already identical on the other side
+func copyable(node *SyncNode, from Side) []*SyncNode {
+	var nodes []*SyncNode
+	var walk func(n *SyncNode)
+	walk = func(n *SyncNode) {
+		source := n.file(from)
+		if source == nil || source.ErrorMessage != "" {
+			return
+		}
+		if source.IsDir || n.Status != compare.Identical || source.RawDiffers {
+			nodes = append(nodes, n)
+		}
+		for _, child := range n.Children {
+			walk(child)
+		}
+	}
+	walk(node)
+	return nodes
+}
+
+// copyNodes copies roots and everything below them from side to the other
+// one, creating the directories above them that are missing there, and
+// updates their entries in place
+func (l *Layout) copyNodes(roots []*SyncNode, from Side) {
+	to := from.other()
+
+	copied, failed := 0, 0
+	var firstErr error
+	var dirs, touched []*SyncNode
+	for _, root := range roots {
+		var parents []*SyncNode
+		parent := root.Parent
+		for ; parent != nil && parent.file(to) == nil; parent = parent.Parent {
+			parents = append([]*SyncNode{parent}, parents...)
+		}
+		if parent != nil {
+			touched = append(touched, parent)
+		}
+
+		for _, n := range append(parents, copyable(root, from)...) {
+			if err := l.copyNode(n, from); err != nil {
+				failed++
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			if n.file(from).IsDir {
+				dirs = append(dirs, n)
+			} else {
+				copied++
+			}
+		}
+	}
+
+	// Adding entries changes directory times, so set them last, deepest first
+	for i := len(dirs) - 1; i >= 0; i-- {
+		source, target := dirs[i].file(from), dirs[i].file(to)
+		if err := fsops.SetModTime(target.Path, source.ModTime); err == nil {
+			target.ModTime = source.ModTime
+		}
+		l.settleStatus(dirs[i], from)
+	}
+	// The folders copied into keep the time they were compared with
+	for _, n := range touched {
+		if target := n.file(to); !target.ModTime.IsZero() {
+			fsops.SetModTime(target.Path, target.ModTime)
+		}
+	}
+
+	l.marked = nil
+	l.refresh()
+
+	if firstErr != nil {
+		l.notify(fmt.Sprintf("[yellow]Copied %s to the %s, %d failed: %s[-]",
+			plural(copied, "file"), to, failed, tview.Escape(firstErr.Error())))
+		return
+	}
+	l.notify(fmt.Sprintf("[green]Copied %s to the %s[-]", plural(copied, "file"), to))
+}
+
+// copyNode copies the entry of n from side to the other one and makes
+// both entries identical, apart from compared attributes that could not
+// be carried over
+func (l *Layout) copyNode(n *SyncNode, from Side) error {
+	to := from.other()
+	source := n.file(from)
+	path := filepath.Join(l.dir(to), n.RelPath)
+
+	var err error
+	switch {
+	case source.IsDir:
+		err = fsops.MakeDir(path, source.Mode)
+	case source.IsSymlink:
+		err = fsops.CopySymlink(source.Path, path)
+	default:
+		err = fsops.CopyFile(source.Path, path)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Owners are only carried over when compared, and only root may change
+	// them; a copy left with other owners stays MetaChanged
+	if l.comparesOwner() {
+		fsops.SetOwner(path, source.UID, source.GID, source.Mode)
+	}
+
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+
+	target := n.file(to)
+	if target == nil {
+		target = &compare.FileInfo{Path: path, RelPath: n.RelPath, Name: source.Name}
+		n.setFile(to, target)
+	}
+	target.IsDir = source.IsDir
+	target.IsSymlink = source.IsSymlink
+	target.LinkTarget = source.LinkTarget
+	target.Size = source.Size
+	target.Hash = source.Hash
+	target.HashAlgorithm = source.HashAlgorithm
+	target.Mode = info.Mode()
+	target.ModTime = info.ModTime()
+	if uid, gid, ok := scanner.FileOwner(info); ok {
+		target.UID = uid
+		target.GID = gid
+	}
+
+	l.releaseCounterpart(n)
+	n.IsDir = source.IsDir
+	l.settleStatus(n, from)
+	return nil
+}
+
+// comparesOwner reports whether the owning user or group is compared
+func (l *Layout) comparesOwner() bool {
+	for _, attr := range l.compareMeta {
+		if attr == compare.MetaOwner || attr == compare.MetaGroup {
+			return true
+		}
+	}
+	return false
+}
+
+// settleStatus gives the entries of n, copied from side, their status:
+// Identical, or MetaChanged if compared attributes still differ
+func (l *Layout) settleStatus(n *SyncNode, from Side) {
+	source, target := n.file(from), n.file(from.other())
+	diff := compare.MetaDiff(source, target, l.compareMeta, l.mtimeTolerance)
+
+	status := compare.Identical
+	if len(diff) > 0 {
+		status = compare.MetaChanged
+	}
+	for _, file := range []*compare.FileInfo{source, target} {
+		resetStatus(file, status)
+		file.MetaDiff = diff
+	}
+	n.Status = status
+}
+
+// releaseCounterpart turns the other end of a moved or renamed node back
+// into a plain new or deleted entry, once n no longer is one end
+func (l *Layout) releaseCounterpart(n *SyncNode) {
+	if n.Status != compare.Moved && n.Status != compare.Renamed {
+		return
+	}
+
+	var counterpart string
+	for _, file := range []*compare.FileInfo{n.SourceFile, n.TargetFile} {
+		if file != nil && file.Counterpart != "" {
+			counterpart = file.Counterpart
+		}
+	}
+	other := findNode(l.syncTree, counterpart)
+	if other == nil || other.Status != n.Status {
+		return
+	}
+
+	other.Status = compare.Deleted
+	if other.SourceFile == nil {
+		other.Status = compare.New
+	}
+	for _, file := range []*compare.FileInfo{other.SourceFile, other.TargetFile} {
+		if file != nil {
+			file.Status = other.Status
+			file.Counterpart = ""
+			file.Similarity = 0
+		}
+	}
+}
+
+// resetStatus gives a file a new status after it was copied or its
+// counterpart deleted, dropping the details of the earlier comparison
+func resetStatus(file *compare.FileInfo, status compare.FileStatus) {
+	file.Status = status
+	file.ErrorMessage = ""
+	file.DiffOffset = 0
+	file.MetaDiff = nil
+	file.RawDiffers = false
+	file.DiffPaths = nil
+	file.Counterpart = ""
+	file.Similarity = 0
+}
+
+// maxListedEntries caps the entries listed in the delete confirmation
+const maxListedEntries = 8
+
+// DeleteSelected asks from which side to delete the marked entries, or
+// else the selected one, then for confirmation, listing what will be
+// removed
+func (l *Layout) DeleteSelected() {
+	roots := l.actionNodes()
+	if len(roots) == 0 {
+		return
+	}
+
+	var buttons []string
+	for _, side := range []Side{SourceSide, TargetSide} {
+		for _, root := range roots {
+			if root.file(side) != nil {
+				buttons = append(buttons, "From "+side.String())
+				break
+			}
+		}
+	}
+
+	modal := tview.NewModal().
+		SetText(fmt.Sprintf("Delete %s from which side?", describeNodes(roots))).
+		AddButtons(append(buttons, "Cancel")).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			l.closeOverlay()
+			switch buttonLabel {
+			case "From source":
+				l.confirmDelete(roots, SourceSide)
+			case "From target":
+				l.confirmDelete(roots, TargetSide)
+			}
+		})
+	l.showOverlay(modal, func(event *tcell.EventKey) *tcell.EventKey {
+		return event
+	})
+}
+
+// confirmDelete asks to delete roots from side, listing the entries
+// removed with them
+func (l *Layout) confirmDelete(roots []*SyncNode, side Side) {
+	var listed []string
+	entries, files := 0, 0
+	var size int64
+	var walk func(n *SyncNode)
+	walk = func(n *SyncNode) {
+		file := n.file(side)
+		if file == nil {
+			return
+		}
+		entries++
+		if !file.IsDir {
+			files++
+			size += file.Size
+		}
+		if len(listed) < maxListedEntries {
+			listed = append(listed, n.RelPath)
+		}
+		if file.LinkTarget != "" {
+			return
+		}
+		for _, child := range n.Children {
+			walk(child)
+		}
+	}
+	for _, root := range roots {
+		walk(root)
+	}
+
+	question := fmt.Sprintf("Delete from the %s:\n\n%s", side, strings.Join(listed, "\n"))
+	if more := entries - len(listed); more > 0 {
+		question += fmt.Sprintf("\n… and %d more", more)
+	}
+	question += fmt.Sprintf("\n\n%s, %s", plural(files, "file"), formatSize(size))
+
+	if anyDir(roots, side) {
+		question += "\n\nFolders still holding entries left out of the comparison are kept."
+	}
+
+	button := "Delete"
+	if l.trashDir != "" {
+		button = "Move to trash"
+		question += "\n\nThey are moved to " + l.trashDir + "."
+	} else {
+		question += "\n\nThis cannot be undone."
+	}
+	l.confirm(question, button, func() {
+		l.deleteNodes(roots, side)
+	})
+}
+
+// deleteTally counts the outcome of deleting entries
+type deleteTally struct {
+	deleted  int
+	kept     int // Directories holding entries that were not scanned
+	failed   int
+	firstErr error
+}
+
+// deleteNodes deletes roots from side, or moves them to the trash
+// directory, and drops them from that side of the tree
+func (l *Layout) deleteNodes(roots []*SyncNode, side Side) {
+	var tally deleteTally
+	for _, root := range roots {
+		l.deleteTree(root, side, &tally)
+	}
+	// The folders deleted from keep the time they were compared with
+	for _, root := range roots {
+		if root.Parent == nil {
+			continue
+		}
+		if parent := root.Parent.file(side); parent != nil && !parent.ModTime.IsZero() {
+			fsops.SetModTime(parent.Path, parent.ModTime)
+		}
+	}
+
+	l.marked = nil
+	l.refresh()
+
+	verb := "Deleted"
+	if l.trashDir != "" {
+		verb = "Moved to trash"
+	}
+	message := fmt.Sprintf("%s %s from the %s", verb, plural(tally.deleted, "entry"), side)
+	if tally.kept > 0 {
+		message += fmt.Sprintf(", kept %s holding entries left out of the comparison", plural(tally.kept, "folder"))
+	}
+	if tally.firstErr != nil {
+		l.notify(fmt.Sprintf("[yellow]%s, %d failed: %s[-]", message, tally.failed, tview.Escape(tally.firstErr.Error())))
+		return
+	}
+	l.notify("[green]" + message + "[-]")
+}
+
+// deleteTree deletes the entry of n on side after the entries below it,
+// dropping each from the tree once it is gone, and reports whether n was
+// deleted. Only scanned entries are deleted: a directory still holding
+// entries left out of the comparison, such as excluded ones, is kept.
+func (l *Layout) deleteTree(n *SyncNode, side Side, tally *deleteTally) bool {
+	file := n.file(side)
+	if file == nil {
+		return true
+	}
+	path := filepath.Join(l.dir(side), n.RelPath)
+
+	// A followed link to a directory is deleted as a link, never its contents
+	if file.IsDir && file.LinkTarget == "" {
+		deleted := true
+		for _, child := range append([]*SyncNode(nil), n.Children...) {
+			if !l.deleteTree(child, side, tally) {
+				deleted = false
+			}
+		}
+		if !deleted {
+			return false
+		}
+
+		empty, err := fsops.IsEmptyDir(path)
+		if err != nil {
+			tally.fail(err)
+			return false
+		}
+		if !empty {
+			tally.kept++
+			return false
+		}
+	}
+
+	var err error
+	if l.trashDir != "" {
+		_, err = fsops.MoveToTrash(path, l.trashDir, filepath.Join(side.String(), n.RelPath))
+	} else {
+		err = fsops.Remove(path)
+	}
+	if err != nil {
+		tally.fail(err)
+		return false
+	}
+
+	l.forget(n, side)
+	tally.deleted++
+	return true
+}
+
+// fail counts an entry that could not be deleted
+func (t *deleteTally) fail(err error) {
+	t.failed++
+	if t.firstErr == nil {
+		t.firstErr = err
+	}
+}
+
+// forget drops the entries of n and everything below it from side. Nodes
+// left without entries on either side are removed from the tree; the
+// others become new or deleted.
+func (l *Layout) forget(n *SyncNode, side Side) {
+	for _, child := range append([]*SyncNode(nil), n.Children...) {
+		l.forget(child, side)
+	}
+	if n.file(side) == nil {
+		return
+	}
+
+	l.releaseCounterpart(n)
+	n.setFile(side, nil)
+
+	remaining := n.file(side.other())
+	if remaining == nil {
+		removeChild(n.Parent, n)
+		return
+	}
+
+	n.IsDir = remaining.IsDir
+	switch {
+	case remaining.ErrorMessage != "":
+		n.Status = compare.Error
+	case side == SourceSide:
+		n.Status = compare.New
+	default:
+		n.Status = compare.Deleted
+	}
+	if n.Status != compare.Error {
+		resetStatus(remaining, n.Status)
+	}
+}
+
+// removeChild removes node from the children of parent
+func removeChild(parent, node *SyncNode) {
+	for i, child := range parent.Children {
+		if child == node {
+			parent.Children = append(parent.Children[:i], parent.Children[i+1:]...)
+			return
+		}
+	}
+}
+
+// refresh recomputes directory statuses and the shown entries after the
+// tree changed, keeping the selection where possible
+func (l *Layout) refresh() {
+	aggregateStatus(l.syncTree)
+	l.setFilter(l.filter)
+}
+
+// formatSize renders a byte count for dialogs, e.g. "12.3 KiB"
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value := float64(size) / unit
+	for _, suffix := range []string{"KiB", "MiB", "GiB"} {
+		if value < unit {
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
+		value /= unit
+	}
+	return fmt.Sprintf("%.1f TiB", value)
+}
+
+// plural renders a count of things, e.g. "1 file" or "3 files"
+func plural(count int, noun string) string {
+	if count == 1 {
+		return "1 " + noun
+	}
+	if strings.HasSuffix(noun, "y") {
+		return fmt.Sprintf("%d %sies", count, strings.TrimSuffix(noun, "y"))
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
@@ -0,0 +1,383 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"folder-diff-v2/internal/compare"
+	"folder-diff-v2/internal/scanner"
+
+	"github.com/rivo/tview"
+)
+
+var testTime = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// writeFiles creates the files in root, keyed by slash-separated relative
+// path; paths ending in "/" are created as empty directories
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for relPath, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(strings.TrimSuffix(relPath, "/")))
+		if strings.HasSuffix(relPath, "/") {
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, testTime, testTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// compareDirs scans and compares two directories the way the command
+// does and returns the layout showing them
+func compareDirs(t *testing.T, sourceDir, targetDir string, scanOpts scanner.Options, opts compare.Options, trashDir string) *Layout {
+	t.Helper()
+	result := compareResult(t, sourceDir, targetDir, scanOpts, opts)
+	return NewLayout(tview.NewApplication(), BuildTree(result.SourceFiles, sourceDir), BuildTree(result.TargetFiles, targetDir),
+		sourceDir, targetDir, result, trashDir)
+}
+
+// compareResult scans and compares two directories
+func compareResult(t *testing.T, sourceDir, targetDir string, scanOpts scanner.Options, opts compare.Options) *compare.ComparisonResult {
+	t.Helper()
+	s, err := scanner.NewScanner(scanOpts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sourceFiles, err := s.ScanDirectory(sourceDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	targetFiles, err := s.ScanDirectory(targetDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.HashAlgorithm = s.HashAlgorithm()
+	result, err := compare.NewComparator(opts).Compare(sourceFiles, targetFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+// statuses returns the status of every entry of the tree below root,
+// keyed by slash-separated relative path
+func statuses(root *SyncNode) map[string]compare.FileStatus {
+	result := make(map[string]compare.FileStatus)
+	var walk func(n *SyncNode)
+	walk = func(n *SyncNode) {
+		for _, child := range n.Children {
+			result[filepath.ToSlash(child.RelPath)] = child.Status
+			walk(child)
+		}
+	}
+	walk(root)
+	return result
+}
+
+// node returns the node at the slash-separated relPath
+func (l *Layout) node(t *testing.T, relPath string) *SyncNode {
+	t.Helper()
+	n := findNode(l.syncTree, filepath.FromSlash(relPath))
+	if n == nil {
+		t.Fatalf("%s is not in the tree", relPath)
+	}
+	return n
+}
+
+func TestCopyNodes(t *testing.T) {
+	tests := []struct {
+		name           string
+		source, target map[string]string
+		copy           []string
+		from           Side
+		want           map[string]compare.FileStatus
+	}{
+		{
+			name:   "new file into missing folder",
+			source: map[string]string{"a/b/new.txt": "new", "same.txt": "same"},
+			target: map[string]string{"same.txt": "same"},
+			copy:   []string{"a/b/new.txt"},
+			from:   SourceSide,
+			want:   map[string]compare.FileStatus{"a": compare.Identical, "a/b": compare.Identical, "a/b/new.txt": compare.Identical, "same.txt": compare.Identical},
+		},
+		{
+			name:   "modified file replaced",
+			source: map[string]string{"dir/file.txt": "new content", "dir/other.txt": "x"},
+			target: map[string]string{"dir/file.txt": "old", "dir/other.txt": "y"},
+			copy:   []string{"dir/file.txt"},
+			from:   SourceSide,
+			want:   map[string]compare.FileStatus{"dir": compare.ContainsChanges, "dir/file.txt": compare.Identical, "dir/other.txt": compare.Modified},
+		},
+		{
+			name:   "folder with everything below it",
+			source: map[string]string{"dir/a.txt": "a", "dir/sub/b.txt": "b", "dir/empty/": ""},
+			target: map[string]string{"dir/a.txt": "old"},
+			copy:   []string{"dir"},
+			from:   SourceSide,
+			want: map[string]compare.FileStatus{"dir": compare.Identical, "dir/a.txt": compare.Identical, "dir/empty": compare.Identical,
+				"dir/sub": compare.Identical, "dir/sub/b.txt": compare.Identical},
+		},
+		{
+			name:   "from the target",
+			source: map[string]string{"keep.txt": "k"},
+			target: map[string]string{"keep.txt": "k", "only/there.txt": "t"},
+			copy:   []string{"only"},
+			from:   TargetSide,
+			want:   map[string]compare.FileStatus{"keep.txt": compare.Identical, "only": compare.Identical, "only/there.txt": compare.Identical},
+		},
+		{
+			name:   "moved file",
+			source: map[string]string{"old/name.txt": "moved"},
+			target: map[string]string{"new/name.txt": "moved"},
+			copy:   []string{"new/name.txt"},
+			from:   TargetSide,
+			want: map[string]compare.FileStatus{"new": compare.Identical, "new/name.txt": compare.Identical,
+				"old": compare.Deleted, "old/name.txt": compare.Deleted},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceDir, targetDir := t.TempDir(), t.TempDir()
+			writeFiles(t, sourceDir, tt.source)
+			writeFiles(t, targetDir, tt.target)
+			scanOpts := scanner.Options{Mode: compare.HashMode}
+			opts := compare.Options{Mode: compare.HashMode}
+			l := compareDirs(t, sourceDir, targetDir, scanOpts, opts, "")
+
+			var roots []*SyncNode
+			for _, relPath := range tt.copy {
+				roots = append(roots, l.node(t, relPath))
+			}
+			l.copyNodes(roots, tt.from)
+
+			got := statuses(l.syncTree)
+			for relPath, want := range tt.want {
+				if got[relPath] != want {
+					t.Errorf("%s: status %s, want %s", relPath, got[relPath], want)
+				}
+			}
+
+			// A new comparison agrees with the updated tree
+			result := compareResult(t, sourceDir, targetDir, scanOpts, opts)
+			fresh := BuildSyncTree(BuildTree(result.SourceFiles, sourceDir), BuildTree(result.TargetFiles, targetDir))
+			rescanned := statuses(fresh)
+			for relPath, status := range got {
+				if rescanned[relPath] != status {
+					t.Errorf("%s: status %s after copying, %s after comparing again", relPath, status, rescanned[relPath])
+				}
+			}
+		})
+	}
+}
+
+func TestCopyNodesMeta(t *testing.T) {
+	sourceDir, targetDir := t.TempDir(), t.TempDir()
+	writeFiles(t, sourceDir, map[string]string{"bin/tool": "#!/bin/sh\n"})
+	writeFiles(t, targetDir, map[string]string{"bin/tool": "#!/bin/sh\n"})
+	for _, dir := range []string{sourceDir, targetDir} {
+		if err := os.Chtimes(filepath.Join(dir, "bin"), testTime, testTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tool := filepath.Join(sourceDir, "bin", "tool")
+	if err := os.Chmod(tool, 0o755|os.ModeSetuid); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(tool, testTime.Add(time.Hour), testTime.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	attrs := []compare.MetaAttr{compare.MetaMode, compare.MetaMtime}
+	wantDiff := "[mode mtime]"
+	if os.Geteuid() == 0 {
+		if err := os.Lchown(tool, 1000, 1000); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(tool, 0o755|os.ModeSetuid); err != nil {
+			t.Fatal(err)
+		}
+		attrs = compare.MetaAttrs
+		wantDiff = "[mode owner group mtime]"
+	}
+
+	scanOpts := scanner.Options{Mode: compare.HashMode}
+	opts := compare.Options{Mode: compare.HashMode, CompareMeta: attrs}
+	l := compareDirs(t, sourceDir, targetDir, scanOpts, opts, "")
+
+	n := l.node(t, "bin/tool")
+	if got := strings.Join([]string{string(n.Status), metaString(n.TargetFile.MetaDiff)}, " "); got != "meta-changed "+wantDiff {
+		t.Fatalf("before copying: %s, want meta-changed %s", got, wantDiff)
+	}
+
+	l.copyNodes([]*SyncNode{n}, SourceSide)
+	if n.Status != compare.Identical || n.SourceFile.MetaDiff != nil || n.TargetFile.MetaDiff != nil {
+		t.Errorf("after copying: status %s, meta diff %v", n.Status, n.TargetFile.MetaDiff)
+	}
+	if got := l.node(t, "bin").Status; got != compare.Identical {
+		t.Errorf("bin: status %s, want %s", got, compare.Identical)
+	}
+
+	result := compareResult(t, sourceDir, targetDir, scanOpts, opts)
+	for _, file := range result.TargetFiles {
+		// The roots are not shown and their times are not kept
+		if file.RelPath != "." && file.Status != compare.Identical {
+			t.Errorf("%s: status %s after comparing again, meta diff %v", file.RelPath, file.Status, file.MetaDiff)
+		}
+	}
+}
+
+// metaString renders attributes like fmt does a slice
+func metaString(attrs []compare.MetaAttr) string {
+	names := make([]string, len(attrs))
+	for i, attr := range attrs {
+		names[i] = string(attr)
+	}
+	return "[" + strings.Join(names, " ") + "]"
+}
+
+func TestDeleteNodes(t *testing.T) {
+	tests := []struct {
+		name           string
+		source, target map[string]string
+		delete         []string
+		side           Side
+		trash          bool
+		want           map[string]compare.FileStatus // Entries left in the tree
+		wantGone       []string                      // Entries deleted from side
+		wantMessage    string
+	}{
+		{
+			name:        "file only on one side",
+			source:      map[string]string{"dir/old.txt": "old", "dir/same.txt": "same"},
+			target:      map[string]string{"dir/same.txt": "same"},
+			delete:      []string{"dir/old.txt"},
+			side:        SourceSide,
+			want:        map[string]compare.FileStatus{"dir": compare.Identical, "dir/same.txt": compare.Identical},
+			wantGone:    []string{"dir/old.txt"},
+			wantMessage: "Deleted 1 entry from the source",
+		},
+		{
+			name:        "file on both sides",
+			source:      map[string]string{"file.txt": "a"},
+			target:      map[string]string{"file.txt": "b"},
+			delete:      []string{"file.txt"},
+			side:        TargetSide,
+			want:        map[string]compare.FileStatus{"file.txt": compare.Deleted},
+			wantGone:    []string{"file.txt"},
+			wantMessage: "Deleted 1 entry from the target",
+		},
+		{
+			name:        "folder with everything below it",
+			source:      map[string]string{"keep.txt": "k"},
+			target:      map[string]string{"keep.txt": "k", "dir/a.txt": "a", "dir/sub/b.txt": "b", "dir/empty/": ""},
+			delete:      []string{"dir"},
+			side:        TargetSide,
+			want:        map[string]compare.FileStatus{"keep.txt": compare.Identical},
+			wantGone:    []string{"dir"},
+			wantMessage: "Deleted 5 entries from the target",
+		},
+		{
+			name:        "folder holding excluded entries",
+			source:      map[string]string{"dir/a.txt": "a", "dir/debug.log": "log", "dir/sub/b.txt": "b"},
+			target:      map[string]string{},
+			delete:      []string{"dir"},
+			side:        SourceSide,
+			want:        map[string]compare.FileStatus{"dir": compare.Deleted},
+			wantGone:    []string{"dir/a.txt", "dir/sub"},
+			wantMessage: "Deleted 3 entries from the source, kept 1 folder holding entries left out of the comparison",
+		},
+		{
+			name:        "marked entries to the trash",
+			source:      map[string]string{"a.txt": "a", "dir/b.txt": "b", "dir/c.txt": "c"},
+			target:      map[string]string{"a.txt": "a", "dir/b.txt": "b", "dir/c.txt": "c"},
+			delete:      []string{"a.txt", "dir/b.txt"},
+			side:        SourceSide,
+			trash:       true,
+			want:        map[string]compare.FileStatus{"a.txt": compare.New, "dir": compare.ContainsChanges, "dir/b.txt": compare.New, "dir/c.txt": compare.Identical},
+			wantGone:    []string{"a.txt", "dir/b.txt"},
+			wantMessage: "Moved to trash 2 entries from the source",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sourceDir, targetDir := t.TempDir(), t.TempDir()
+			writeFiles(t, sourceDir, tt.source)
+			writeFiles(t, targetDir, tt.target)
+			var trashDir string
+			if tt.trash {
+				trashDir = t.TempDir()
+			}
+
+			// Folder times are compared, so the folders deleted from must
+			// keep theirs
+			scanOpts := scanner.Options{Mode: compare.HashMode, ExcludePatterns: []string{"*.log"}}
+			opts := compare.Options{Mode: compare.HashMode, CompareMeta: []compare.MetaAttr{compare.MetaMtime}}
+			for _, dir := range []string{sourceDir, targetDir} {
+				err := filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+					if err == nil && entry.IsDir() && path != dir {
+						err = os.Chtimes(path, testTime, testTime)
+					}
+					return err
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			l := compareDirs(t, sourceDir, targetDir, scanOpts, opts, trashDir)
+
+			var roots []*SyncNode
+			for _, relPath := range tt.delete {
+				roots = append(roots, l.node(t, relPath))
+			}
+			l.deleteNodes(roots, tt.side)
+
+			got := statuses(l.syncTree)
+			if len(got) != len(tt.want) {
+				t.Errorf("tree holds %v, want %v", got, tt.want)
+			}
+			for relPath, want := range tt.want {
+				if got[relPath] != want {
+					t.Errorf("%s: status %s, want %s", relPath, got[relPath], want)
+				}
+			}
+			if message := l.detailBar.GetText(true); !strings.Contains(message, tt.wantMessage) {
+				t.Errorf("message %q, want %q", message, tt.wantMessage)
+			}
+
+			for _, relPath := range tt.wantGone {
+				if _, err := os.Lstat(filepath.Join(l.dir(tt.side), filepath.FromSlash(relPath))); !os.IsNotExist(err) {
+					t.Errorf("%s is still on the %s", relPath, tt.side)
+				}
+				if tt.trash {
+					trashed := filepath.Join(trashDir, tt.side.String(), filepath.FromSlash(relPath))
+					if _, err := os.Lstat(trashed); err != nil {
+						t.Errorf("%s is not in the trash: %v", relPath, err)
+					}
+				}
+			}
+
+			// A new comparison agrees with the updated tree
+			result := compareResult(t, sourceDir, targetDir, scanOpts, opts)
+			fresh := statuses(BuildSyncTree(BuildTree(result.SourceFiles, sourceDir), BuildTree(result.TargetFiles, targetDir)))
+			for relPath, status := range got {
+				if fresh[relPath] != status {
+					t.Errorf("%s: status %s after deleting, %s after comparing again", relPath, status, fresh[relPath])
+				}
+			}
+		})
+	}
+}
@@ -14,15 +14,17 @@ type App struct {
 	result    *compare.ComparisonResult
 	sourceDir string
 	targetDir string
+	trashDir  string
 }
 
 // NewApp creates a new TUI application
-func NewApp(result *compare.ComparisonResult, sourceDir, targetDir string) *App {
+func NewApp(result *compare.ComparisonResult, sourceDir, targetDir, trashDir string) *App {
 	return &App{
 		app:       tview.NewApplication(),
 		result:    result,
 		sourceDir: sourceDir,
 		targetDir: targetDir,
+		trashDir:  trashDir,
 	}
 }
 
@@ -33,10 +35,15 @@ func (a *App) Run() error {
 	targetTree := BuildTree(a.result.TargetFiles, a.targetDir)
 
 	// Create synchronized layout
-	a.layout = NewLayout(a.app, sourceTree, targetTree, a.sourceDir, a.targetDir)
+	a.layout = NewLayout(a.app, sourceTree, targetTree, a.sourceDir, a.targetDir, a.result, a.trashDir)
 
 	// Set up global key bindings
 	a.app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		// A view shown over the tree handles its own keys
+		if input := a.layout.OverlayInput(); input != nil {
+			return input(event)
+		}
+
 		switch event.Key() {
 		case tcell.KeyEsc:
 			a.app.Stop()
@@ -48,7 +55,7 @@ func (a *App) Run() error {
 			a.layout.MoveDown()
 			return nil
 		case tcell.KeyEnter:
-			a.layout.ToggleExpand()
+			a.layout.OpenSelected()
 			return nil
 		case tcell.KeyCtrlC:
 			a.app.Stop()
@@ -68,6 +75,51 @@ func (a *App) Run() error {
 		case 'd', 'D':
 			a.layout.JumpToNextDiff()
 			return nil
+		case 'o':
+			a.layout.JumpToCounterpart()
+			return nil
+		case '/':
+			a.layout.StartSearch()
+			return nil
+		case 'n':
+			a.layout.NextMatch()
+			return nil
+		case 'N':
+			a.layout.PreviousMatch()
+			return nil
+		case 'g':
+			a.layout.GoToPath()
+			return nil
+		case '>':
+			a.layout.CopySelected(SourceSide)
+			return nil
+		case '<':
+			a.layout.CopySelected(TargetSide)
+			return nil
+		case 'x':
+			a.layout.DeleteSelected()
+			return nil
+		case 'e':
+			a.layout.ExportSelected()
+			return nil
+		case 'm':
+			a.layout.ToggleMark()
+			return nil
+		case 'M':
+			a.layout.MarkDirectory()
+			return nil
+		case '*':
+			a.layout.MarkStatus()
+			return nil
+		case 'u':
+			a.layout.ClearMarks()
+			return nil
+		case 'f':
+			a.layout.ToggleFilter()
+			return nil
+		case 'F':
+			a.layout.CycleStatusFilter()
+			return nil
 		case 'k':
 			a.layout.MoveUp()
 			return nil
@@ -0,0 +1,386 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"folder-diff-v2/internal/compare"
+	"folder-diff-v2/internal/textdiff"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// maxDiffFileSize caps the size of files read into the diff view
+const maxDiffFileSize = 8 << 20
+
+// diffContext is the number of unchanged lines shown around changes
+const diffContext = 3
+
+// DiffView shows a line diff of a source and a target file, unified or
+// side by side, with colored hunks
+type DiffView struct {
+	root      *tview.Flex
+	panes     *tview.Flex
+	unified   *tview.TextView
+	left      *tview.TextView
+	right     *tview.TextView
+	statusBar *tview.TextView
+
+	sourcePath string
+	targetPath string
+	lines      []textdiff.Line
+	hunks      []textdiff.Hunk
+	message    string // Shown instead of a diff, e.g. for binary files
+
+	sideBySide bool
+	hunkRows   []int // Row at which each hunk starts in the current mode
+	rowCount   int
+	row        int
+	column     int
+
+	onClose func()
+}
+
+// NewDiffView creates a diff view of the source and target file. onClose
+// is called when the user leaves the view.
+func NewDiffView(source, target *compare.FileInfo, onClose func()) *DiffView {
+	d := &DiffView{
+		sourcePath: source.RelPath,
+		targetPath: target.RelPath,
+		onClose:    onClose,
+	}
+	d.load(source.Path, target.Path)
+
+	newPane := func() *tview.TextView {
+		view := tview.NewTextView().
+			SetDynamicColors(true).
+			SetWrap(false)
+		view.SetBorder(true)
+		return view
+	}
+	d.unified = newPane()
+	d.unified.SetTitle(" Diff: " + tview.Escape(d.sourcePath) + " ")
+	d.left = newPane()
+	d.left.SetTitle(" Source: " + tview.Escape(d.sourcePath) + " ")
+	d.right = newPane()
+	d.right.SetTitle(" Target: " + tview.Escape(d.targetPath) + " ")
+
+	d.statusBar = tview.NewTextView().
+		SetDynamicColors(true)
+
+	d.panes = tview.NewFlex()
+	d.root = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(d.panes, 0, 1, false).
+		AddItem(d.statusBar, 1, 0, false)
+
+	// Scroll all panes together instead of letting each scroll on its own
+	d.root.SetMouseCapture(func(action tview.MouseAction, event *tcell.EventMouse) (tview.MouseAction, *tcell.EventMouse) {
+		switch action {
+		case tview.MouseScrollUp:
+			d.scrollTo(d.row-3, d.column)
+			return action, nil
+		case tview.MouseScrollDown:
+			d.scrollTo(d.row+3, d.column)
+			return action, nil
+		}
+		return action, event
+	})
+
+	d.render()
+	return d
+}
+
+// load reads both files and diffs them, or sets a message explaining why
+// they cannot be shown as text
+func (d *DiffView) load(sourcePath, targetPath string) {
+	source, err := readDiffFile(sourcePath)
+	if err != nil {
+		d.message = "[yellow]" + tview.Escape(err.Error()) + "[-]"
+		return
+	}
+	target, err := readDiffFile(targetPath)
+	if err != nil {
+		d.message = "[yellow]" + tview.Escape(err.Error()) + "[-]"
+		return
+	}
+
+	if compare.IsBinary(source) || compare.IsBinary(target) {
+		d.message = "Binary files differ"
+		if string(source) == string(target) {
+			d.message = "Binary files are identical"
+		}
+		return
+	}
+
+	d.lines = textdiff.Diff(textdiff.SplitLines(source), textdiff.SplitLines(target))
+	d.hunks = textdiff.Hunks(d.lines, diffContext)
+	if len(d.hunks) == 0 {
+		d.message = "No line differences"
+	}
+}
+
+// readDiffFile reads a file for the diff view, refusing very large ones
+func readDiffFile(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > maxDiffFileSize {
+		return nil, fmt.Errorf("%s is too large to diff (%d MiB max)", path, maxDiffFileSize>>20)
+	}
+	return os.ReadFile(path)
+}
+
+// GetRoot returns the root primitive
+func (d *DiffView) GetRoot() tview.Primitive {
+	return d.root
+}
+
+// HandleKey handles a key press while the view is shown
+func (d *DiffView) HandleKey(event *tcell.EventKey) *tcell.EventKey {
+	_, _, _, height := d.unified.GetInnerRect()
+	page := max(height-1, 1)
+
+	switch event.Key() {
+	case tcell.KeyEsc:
+		d.onClose()
+	case tcell.KeyUp:
+		d.scrollTo(d.row-1, d.column)
+	case tcell.KeyDown:
+		d.scrollTo(d.row+1, d.column)
+	case tcell.KeyPgUp:
+		d.scrollTo(d.row-page, d.column)
+	case tcell.KeyPgDn:
+		d.scrollTo(d.row+page, d.column)
+	case tcell.KeyHome:
+		d.scrollTo(0, 0)
+	case tcell.KeyEnd:
+		d.scrollTo(d.rowCount-1, d.column)
+	case tcell.KeyLeft:
+		d.scrollTo(d.row, d.column-8)
+	case tcell.KeyRight:
+		d.scrollTo(d.row, d.column+8)
+	case tcell.KeyRune:
+		switch event.Rune() {
+		case 'q', 'Q':
+			d.onClose()
+		case 'k':
+			d.scrollTo(d.row-1, d.column)
+		case 'j':
+			d.scrollTo(d.row+1, d.column)
+		case ' ':
+			d.scrollTo(d.row+page, d.column)
+		case 'g':
+			d.scrollTo(0, 0)
+		case 'G':
+			d.scrollTo(d.rowCount-1, d.column)
+		case 'n':
+			d.nextHunk()
+		case 'p':
+			d.previousHunk()
+		case 's':
+			d.toggleSideBySide()
+		}
+	}
+	return nil
+}
+
+// nextHunk scrolls to the first hunk below the top row
+func (d *DiffView) nextHunk() {
+	for _, row := range d.hunkRows {
+		if row > d.row {
+			d.scrollTo(row, 0)
+			return
+		}
+	}
+}
+
+// previousHunk scrolls to the last hunk above the top row
+func (d *DiffView) previousHunk() {
+	for i := len(d.hunkRows) - 1; i >= 0; i-- {
+		if d.hunkRows[i] < d.row {
+			d.scrollTo(d.hunkRows[i], 0)
+			return
+		}
+	}
+}
+
+// toggleSideBySide switches between unified and side-by-side display,
+// staying at the current hunk
+func (d *DiffView) toggleSideBySide() {
+	hunk := d.currentHunk()
+	d.sideBySide = !d.sideBySide
+	d.render()
+	if hunk >= 0 {
+		d.scrollTo(d.hunkRows[hunk], d.column)
+	}
+}
+
+// currentHunk returns the index of the hunk shown at the top row, or -1
+func (d *DiffView) currentHunk() int {
+	current := -1
+	for i, row := range d.hunkRows {
+		if row <= d.row {
+			current = i
+		}
+	}
+	return current
+}
+
+// scrollTo scrolls all panes to row and column, within bounds
+func (d *DiffView) scrollTo(row, column int) {
+	d.row = max(min(row, d.rowCount-1), 0)
+	d.column = max(column, 0)
+	d.unified.ScrollTo(d.row, d.column)
+	d.left.ScrollTo(d.row, d.column)
+	d.right.ScrollTo(d.row, d.column)
+	d.statusBar.SetText(d.statusText())
+}
+
+// render fills the panes for the current display mode
+func (d *DiffView) render() {
+	d.panes.Clear()
+	if d.sideBySide {
+		d.panes.AddItem(d.left, 0, 1, false).
+			AddItem(d.right, 0, 1, false)
+	} else {
+		d.panes.AddItem(d.unified, 0, 1, false)
+	}
+
+	switch {
+	case d.message != "":
+		d.unified.SetText(d.message)
+		d.left.SetText(d.message)
+		d.right.SetText(d.message)
+		d.hunkRows = nil
+		d.rowCount = 1
+	case d.sideBySide:
+		left, right, hunkRows := d.sideBySideRows()
+		d.left.SetText(strings.Join(left, "\n"))
+		d.right.SetText(strings.Join(right, "\n"))
+		d.hunkRows = hunkRows
+		d.rowCount = len(left)
+	default:
+		rows, hunkRows := d.unifiedRows()
+		d.unified.SetText(strings.Join(rows, "\n"))
+		d.hunkRows = hunkRows
+		d.rowCount = len(rows)
+	}
+
+	d.scrollTo(d.row, d.column)
+}
+
+// unifiedRows renders the hunks as in `diff -u`, with line numbers of
+// both files
+func (d *DiffView) unifiedRows() (rows []string, hunkRows []int) {
+	for _, hunk := range d.hunks {
+		hunkRows = append(hunkRows, len(rows))
+		rows = append(rows, "[aqua]"+hunkHeader(d.lines[hunk.Start:hunk.End])+"[-]")
+
+		for _, line := range d.lines[hunk.Start:hunk.End] {
+			numbers := lineNumber(line.OldLine) + " " + lineNumber(line.NewLine)
+			text := tview.Escape(line.Text)
+			switch line.Kind {
+			case textdiff.Delete:
+				rows = append(rows, fmt.Sprintf("[gray]%s[-] [red]-%s[-]", numbers, text))
+			case textdiff.Insert:
+				rows = append(rows, fmt.Sprintf("[gray]%s[-] [green]+%s[-]", numbers, text))
+			default:
+				rows = append(rows, fmt.Sprintf("[gray]%s[-]  %s", numbers, text))
+			}
+		}
+	}
+	return rows, hunkRows
+}
+
+// sideBySideRows renders the hunks as two aligned columns. Deleted and
+// inserted lines of a change face each other; blank rows fill the shorter
+// side.
+func (d *DiffView) sideBySideRows() (left, right []string, hunkRows []int) {
+	for _, hunk := range d.hunks {
+		lines := d.lines[hunk.Start:hunk.End]
+		header := "[aqua]" + hunkHeader(lines) + "[-]"
+		hunkRows = append(hunkRows, len(left))
+		left = append(left, header)
+		right = append(right, header)
+
+		for i := 0; i < len(lines); {
+			if lines[i].Kind == textdiff.Equal {
+				row := fmt.Sprintf("[gray]%s[-] %s", lineNumber(lines[i].OldLine), tview.Escape(lines[i].Text))
+				left = append(left, row)
+				right = append(right, fmt.Sprintf("[gray]%s[-] %s", lineNumber(lines[i].NewLine), tview.Escape(lines[i].Text)))
+				i++
+				continue
+			}
+
+			var deleted, inserted []string
+			for ; i < len(lines) && lines[i].Kind != textdiff.Equal; i++ {
+				line := lines[i]
+				if line.Kind == textdiff.Delete {
+					deleted = append(deleted, fmt.Sprintf("[gray]%s[-] [red]%s[-]", lineNumber(line.OldLine), tview.Escape(line.Text)))
+				} else {
+					inserted = append(inserted, fmt.Sprintf("[gray]%s[-] [green]%s[-]", lineNumber(line.NewLine), tview.Escape(line.Text)))
+				}
+			}
+			for j := 0; j < max(len(deleted), len(inserted)); j++ {
+				left = append(left, rowAt(deleted, j))
+				right = append(right, rowAt(inserted, j))
+			}
+		}
+	}
+	return left, right, hunkRows
+}
+
+// rowAt returns rows[i], or an empty row past the end
+func rowAt(rows []string, i int) string {
+	if i < len(rows) {
+		return rows[i]
+	}
+	return ""
+}
+
+// lineNumber formats a line number for the gutter; 0 leaves it blank
+func lineNumber(n int) string {
+	if n == 0 {
+		return "     "
+	}
+	return fmt.Sprintf("%5d", n)
+}
+
+// hunkHeader returns the "@@ -start,count +start,count @@" header of the
+// diff lines of a hunk
+func hunkHeader(lines []textdiff.Line) string {
+	oldStart, newStart := 0, 0
+	oldCount, newCount := 0, 0
+	for _, line := range lines {
+		if line.OldLine > 0 {
+			if oldStart == 0 {
+				oldStart = line.OldLine
+			}
+			oldCount++
+		}
+		if line.NewLine > 0 {
+			if newStart == 0 {
+				newStart = line.NewLine
+			}
+			newCount++
+		}
+	}
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", oldStart, oldCount, newStart, newCount)
+}
+
+// statusText returns the status bar text: the position among the hunks
+// and key hints
+func (d *DiffView) statusText() string {
+	mode := "Side-by-side"
+	if d.sideBySide {
+		mode = "Unified"
+	}
+	text := fmt.Sprintf("[yellow]n/p[white] Next/Prev Hunk  [yellow]s[white] %s  [yellow]←→[white] Scroll  [yellow]q/Esc[white] Back", mode)
+	if len(d.hunkRows) > 0 {
+		text = fmt.Sprintf("[::b]Hunk %d/%d[::-]   |   ", max(d.currentHunk()+1, 1), len(d.hunkRows)) + text
+	}
+	return text
+}
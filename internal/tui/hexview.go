@@ -0,0 +1,493 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"folder-diff-v2/internal/compare"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// hexRowBytes is the number of bytes shown per hex dump row
+const hexRowBytes = 16
+
+// hexWindowRows is the number of rows rendered from the top row on. Only
+// this window of the files is read, however large they are.
+const hexWindowRows = 200
+
+// hexContextRows is the number of rows kept above a differing region
+// jumped to
+const hexContextRows = 2
+
+// hexChunk is the read size used when scanning for differences
+const hexChunk = 64 << 10
+
+// HexView shows hex dumps of a source and a target file side by side,
+// aligned by offset, with differing bytes highlighted
+type HexView struct {
+	root      *tview.Flex
+	left      *tview.TextView
+	right     *tview.TextView
+	statusBar *tview.TextView
+
+	source  *os.File
+	target  *os.File
+	size    int64 // Size of the larger file
+	differ  int64 // Number of differing bytes, once counted
+	counted bool
+	message string
+	done    chan struct{}  // Closed when the view is left, to stop counting
+	counter sync.WaitGroup // Tracks the counting goroutine
+
+	row    int64 // Top row
+	column int
+	region int64 // Offset of the region jumped to last, or -1
+
+	onClose func()
+}
+
+// NewHexView creates a hex view of the source and target file. Differing
+// bytes are counted in the background and reported through app. onClose
+// is called when the user leaves the view.
+func NewHexView(app *tview.Application, source, target *compare.FileInfo, onClose func()) *HexView {
+	h := &HexView{
+		region:  -1,
+		done:    make(chan struct{}),
+		onClose: onClose,
+	}
+
+	newPane := func(file *compare.FileInfo, side string) *tview.TextView {
+		view := tview.NewTextView().
+			SetDynamicColors(true).
+			SetWrap(false)
+		view.SetBorder(true).SetTitle(fmt.Sprintf(" %s: %s (%d bytes) ", side, tview.Escape(file.RelPath), file.Size))
+		return view
+	}
+	h.left = newPane(source, "Source")
+	h.right = newPane(target, "Target")
+
+	h.statusBar = tview.NewTextView().
+		SetDynamicColors(true)
+
+	h.root = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(tview.NewFlex().
+			AddItem(h.left, 0, 1, false).
+			AddItem(h.right, 0, 1, false), 0, 1, false).
+		AddItem(h.statusBar, 1, 0, false)
+
+	h.root.SetMouseCapture(func(action tview.MouseAction, event *tcell.EventMouse) (tview.MouseAction, *tcell.EventMouse) {
+		switch action {
+		case tview.MouseScrollUp:
+			h.scrollTo(h.row-3, h.column)
+			return action, nil
+		case tview.MouseScrollDown:
+			h.scrollTo(h.row+3, h.column)
+			return action, nil
+		}
+		return action, event
+	})
+
+	if err := h.open(source.Path, target.Path); err != nil {
+		h.message = err.Error()
+	} else {
+		h.counter.Add(1)
+		go h.count(app)
+	}
+	h.render()
+	return h
+}
+
+// open opens both files
+func (h *HexView) open(sourcePath, targetPath string) error {
+	var err error
+	if h.source, err = os.Open(sourcePath); err != nil {
+		return err
+	}
+	if h.target, err = os.Open(targetPath); err != nil {
+		return err
+	}
+
+	for _, file := range []*os.File{h.source, h.target} {
+		info, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		h.size = max(h.size, info.Size())
+	}
+	return nil
+}
+
+// count counts the bytes in which the files differ, off the UI goroutine so
+// that large files do not hold it up. Once the first difference is found,
+// the view jumps to it unless the user has scrolled already.
+func (h *HexView) count(app *tview.Application) {
+	defer h.counter.Done()
+
+	var differ int64
+	first := int64(-1)
+	for offset := int64(0); offset < h.size; offset += hexChunk {
+		select {
+		case <-h.done:
+			return
+		default:
+		}
+
+		source, target, err := h.readBoth(offset, hexChunk)
+		if err != nil {
+			h.update(app, func() {
+				h.setError(err)
+				h.render()
+			})
+			return
+		}
+		for i := 0; i < max(len(source), len(target)); i++ {
+			if !bytesDiffer(source, target, i) {
+				continue
+			}
+			differ++
+			if first < 0 {
+				first = offset + int64(i)
+				at := first
+				h.update(app, func() {
+					if h.row == 0 && h.region < 0 {
+						h.jumpTo(at)
+						h.render()
+					}
+				})
+			}
+		}
+	}
+
+	h.update(app, func() {
+		h.differ = differ
+		h.counted = true
+		h.render()
+	})
+}
+
+// update runs fn on the UI goroutine and redraws, unless the view has been
+// left by then. Queueing waits for the UI goroutine, which may itself be
+// waiting in close for counting to stop, so it is done on the side.
+func (h *HexView) update(app *tview.Application, fn func()) {
+	select {
+	case <-h.done:
+		return
+	default:
+	}
+	go app.QueueUpdateDraw(func() {
+		select {
+		case <-h.done:
+		default:
+			fn()
+		}
+	})
+}
+
+// close stops counting, waits for the counting goroutine to finish, closes
+// both files and leaves the view
+func (h *HexView) close() {
+	close(h.done)
+	h.counter.Wait()
+	for _, file := range []*os.File{h.source, h.target} {
+		if file != nil {
+			file.Close()
+		}
+	}
+	h.onClose()
+}
+
+// readBoth reads up to n bytes at offset from both files; either is
+// shorter where its file ends
+func (h *HexView) readBoth(offset int64, n int) (source, target []byte, err error) {
+	if source, err = readAt(h.source, offset, n); err != nil {
+		return nil, nil, err
+	}
+	if target, err = readAt(h.target, offset, n); err != nil {
+		return nil, nil, err
+	}
+	return source, target, nil
+}
+
+// readAt reads up to n bytes of file at offset
+func readAt(file *os.File, offset int64, n int) ([]byte, error) {
+	buf := make([]byte, n)
+	read, err := file.ReadAt(buf, offset)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return buf[:read], nil
+}
+
+// bytesDiffer reports whether the byte at i differs between both sides.
+// A byte present on one side only differs.
+func bytesDiffer(source, target []byte, i int) bool {
+	return i >= len(source) || i >= len(target) || source[i] != target[i]
+}
+
+// scan returns the first offset from start on, going forward or backward,
+// at which the files differ (or agree, if differ is false), or -1
+func (h *HexView) scan(start int64, forward, differ bool) (int64, error) {
+	if forward {
+		for offset := max(start, 0); offset < h.size; offset += hexChunk {
+			source, target, err := h.readBoth(offset, hexChunk)
+			if err != nil {
+				return -1, err
+			}
+			for i := 0; i < max(len(source), len(target)); i++ {
+				if bytesDiffer(source, target, i) == differ {
+					return offset + int64(i), nil
+				}
+			}
+		}
+		return -1, nil
+	}
+
+	for end := min(start, h.size-1) + 1; end > 0; end -= hexChunk {
+		begin := max(end-hexChunk, 0)
+		source, target, err := h.readBoth(begin, int(end-begin))
+		if err != nil {
+			return -1, err
+		}
+		for i := int(end-begin) - 1; i >= 0; i-- {
+			if bytesDiffer(source, target, i) == differ {
+				return begin + int64(i), nil
+			}
+		}
+	}
+	return -1, nil
+}
+
+// GetRoot returns the root primitive
+func (h *HexView) GetRoot() tview.Primitive {
+	return h.root
+}
+
+// HandleKey handles a key press while the view is shown
+func (h *HexView) HandleKey(event *tcell.EventKey) *tcell.EventKey {
+	_, _, _, height := h.left.GetInnerRect()
+	page := int64(max(height-1, 1))
+
+	switch event.Key() {
+	case tcell.KeyEsc:
+		h.close()
+	case tcell.KeyUp:
+		h.scrollTo(h.row-1, h.column)
+	case tcell.KeyDown:
+		h.scrollTo(h.row+1, h.column)
+	case tcell.KeyPgUp:
+		h.scrollTo(h.row-page, h.column)
+	case tcell.KeyPgDn:
+		h.scrollTo(h.row+page, h.column)
+	case tcell.KeyHome:
+		h.scrollTo(0, 0)
+	case tcell.KeyEnd:
+		h.scrollTo(h.lastRow(), h.column)
+	case tcell.KeyLeft:
+		h.scrollTo(h.row, h.column-8)
+	case tcell.KeyRight:
+		h.scrollTo(h.row, h.column+8)
+	case tcell.KeyRune:
+		switch event.Rune() {
+		case 'q', 'Q':
+			h.close()
+		case 'k':
+			h.scrollTo(h.row-1, h.column)
+		case 'j':
+			h.scrollTo(h.row+1, h.column)
+		case ' ':
+			h.scrollTo(h.row+page, h.column)
+		case 'g':
+			h.scrollTo(0, 0)
+		case 'G':
+			h.scrollTo(h.lastRow(), h.column)
+		case 'n':
+			h.nextRegion()
+			h.render()
+		case 'p':
+			h.previousRegion()
+			h.render()
+		}
+	}
+	return nil
+}
+
+// nextRegion jumps to the start of the next run of differing bytes after
+// the one jumped to last, or after the top row
+func (h *HexView) nextRegion() {
+	from := h.row * hexRowBytes
+	if h.region >= 0 {
+		end, err := h.scan(h.region, true, false)
+		if err != nil || end < 0 {
+			h.setError(err)
+			return
+		}
+		from = end
+	}
+
+	next, err := h.scan(from, true, true)
+	if err != nil || next < 0 {
+		h.setError(err)
+		return
+	}
+	h.jumpTo(next)
+}
+
+// previousRegion jumps to the start of the run of differing bytes before
+// the one jumped to last, or before the top row
+func (h *HexView) previousRegion() {
+	from := h.row * hexRowBytes
+	if h.region >= 0 {
+		from = h.region
+	}
+
+	last, err := h.scan(from-1, false, true)
+	if err != nil || last < 0 {
+		h.setError(err)
+		return
+	}
+	start, err := h.scan(last, false, false)
+	if err != nil {
+		h.setError(err)
+		return
+	}
+	h.jumpTo(start + 1)
+}
+
+// jumpTo shows the region starting at offset near the top
+func (h *HexView) jumpTo(offset int64) {
+	h.region = offset
+	h.row = max(offset/hexRowBytes-hexContextRows, 0)
+}
+
+// setError shows a read error in the status bar; nil errors are ignored
+func (h *HexView) setError(err error) {
+	if err != nil {
+		h.message = err.Error()
+	}
+}
+
+// lastRow returns the index of the last row of the larger file
+func (h *HexView) lastRow() int64 {
+	return max((h.size-1)/hexRowBytes, 0)
+}
+
+// scrollTo scrolls both panes to row and column, within bounds
+func (h *HexView) scrollTo(row int64, column int) {
+	h.row = max(min(row, h.lastRow()), 0)
+	h.column = max(column, 0)
+	h.region = -1
+	h.render()
+}
+
+// render fills both panes with the rows of the window at the top row
+func (h *HexView) render() {
+	var left, right []string
+	if h.source != nil && h.target != nil {
+		offset := h.row * hexRowBytes
+		source, target, err := h.readBoth(offset, hexWindowRows*hexRowBytes)
+		h.setError(err)
+
+		for start := 0; start < max(len(source), len(target)); start += hexRowBytes {
+			sourceRow := rowBytes(source, start)
+			targetRow := rowBytes(target, start)
+			left = append(left, hexRow(offset+int64(start), sourceRow, targetRow))
+			right = append(right, hexRow(offset+int64(start), targetRow, sourceRow))
+		}
+	}
+
+	h.left.SetText(strings.Join(left, "\n")).ScrollTo(0, h.column)
+	h.right.SetText(strings.Join(right, "\n")).ScrollTo(0, h.column)
+	h.statusBar.SetText(h.statusText())
+}
+
+// rowBytes returns the bytes of the row starting at start within data
+func rowBytes(data []byte, start int) []byte {
+	if start >= len(data) {
+		return nil
+	}
+	return data[start:min(start+hexRowBytes, len(data))]
+}
+
+// hexRow renders one row of a hex dump, e.g.
+// "00000010  7f 45 4c 46 02 01 01 00  00 00 00 00 00 00 00 00  |.ELF............|",
+// highlighting the bytes that differ from other
+func hexRow(offset int64, data, other []byte) string {
+	var hex, ascii strings.Builder
+	fmt.Fprintf(&hex, "[gray]%08x[-]  ", offset)
+
+	// ASCII runs are escaped as a whole, so that bytes cannot form tags
+	var run []byte
+	runDiffers := false
+	flush := func() {
+		if len(run) == 0 {
+			return
+		}
+		text := tview.Escape(string(run))
+		if runDiffers {
+			text = "[black:red]" + text + "[-:-]"
+		}
+		ascii.WriteString(text)
+		run = run[:0]
+	}
+
+	for i := 0; i < hexRowBytes; i++ {
+		if i == hexRowBytes/2 {
+			hex.WriteString(" ")
+		}
+		if i >= len(data) {
+			hex.WriteString("   ")
+			continue
+		}
+
+		differs := bytesDiffer(data, other, i)
+		if differs {
+			fmt.Fprintf(&hex, "[black:red]%02x[-:-] ", data[i])
+		} else {
+			fmt.Fprintf(&hex, "%02x ", data[i])
+		}
+
+		if differs != runDiffers {
+			flush()
+			runDiffers = differs
+		}
+		char := data[i]
+		if char < 0x20 || char > 0x7e {
+			char = '.'
+		}
+		run = append(run, char)
+	}
+	flush()
+
+	if len(data) == 0 {
+		return hex.String()
+	}
+	return hex.String() + " |" + ascii.String() + "|"
+}
+
+// statusText returns the status bar text: the differing byte count, the
+// region jumped to last and key hints
+func (h *HexView) statusText() string {
+	keys := "[yellow]n/p[white] Next/Prev Region  [yellow]←→[white] Scroll  [yellow]q/Esc[white] Back"
+	if h.message != "" {
+		return "[yellow]" + tview.Escape(h.message) + "[white]   |   " + keys
+	}
+
+	var text string
+	switch {
+	case !h.counted:
+		text = "[::b]Counting differing bytes…[::-]"
+	case h.size == 0:
+		text = fmt.Sprintf("[::b]%d differing bytes[::-]", h.differ)
+	default:
+		text = fmt.Sprintf("[::b]%d of %d bytes differ (%.1f%%)[::-]", h.differ, h.size, float64(h.differ)*100/float64(h.size))
+	}
+	if h.region >= 0 {
+		text += fmt.Sprintf("  at 0x%x", h.region)
+	}
+	return text + "   |   " + keys
+}
@@ -3,6 +3,7 @@ package tui
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"folder-diff-v2/internal/compare"
 
@@ -12,31 +13,46 @@ import (
 
 // Layout manages the synchronized dual-pane TUI layout
 type Layout struct {
-	app           *tview.Application
-	root          *tview.Flex
-	sourceView    *tview.TextView
-	targetView    *tview.TextView
-	statusBar     *tview.TextView
-	helpModal     *tview.Modal
-	syncTree      *SyncNode
-	flatNodes     []*SyncNode
-	currentIndex  int
-	sourceDir     string
-	targetDir     string
+	app            *tview.Application
+	root           *tview.Flex
+	sourceView     *tview.TextView
+	targetView     *tview.TextView
+	statusBar      *tview.TextView
+	detailBar      *tview.TextView
+	helpModal      *tview.Modal
+	syncTree       *SyncNode
+	flatNodes      []*SyncNode
+	currentIndex   int
+	sourceDir      string
+	targetDir      string
+	mode           compare.ComparisonMode
+	compareMeta    []compare.MetaAttr // Attributes compared besides content
+	mtimeTolerance time.Duration
+	filter         Filter
+	trashDir       string // Deleted entries are moved here, if set
+	prompt         *tview.InputField
+	query          string             // Search query, "" if none
+	matches        map[*SyncNode]bool // Entries matching the query
+	marked         map[*SyncNode]bool // Entries marked for batch actions
+	overlay        func(event *tcell.EventKey) *tcell.EventKey
 }
 
 // NewLayout creates a new synchronized layout
-func NewLayout(app *tview.Application, sourceRoot, targetRoot *compare.FileInfo, sourceDir, targetDir string) *Layout {
+func NewLayout(app *tview.Application, sourceRoot, targetRoot *compare.FileInfo, sourceDir, targetDir string, result *compare.ComparisonResult, trashDir string) *Layout {
 	l := &Layout{
-		app:          app,
-		currentIndex: 0,
-		sourceDir:    sourceDir,
-		targetDir:    targetDir,
+		app:            app,
+		currentIndex:   0,
+		mode:           result.Mode,
+		compareMeta:    result.CompareMeta,
+		mtimeTolerance: result.MtimeTolerance,
+		trashDir:       trashDir,
+		sourceDir:      sourceDir,
+		targetDir:      targetDir,
 	}
 
 	// Build synchronized tree
 	l.syncTree = BuildSyncTree(sourceRoot, targetRoot)
-	l.flatNodes = FlattenTree(l.syncTree)
+	l.flatNodes = FlattenTree(l.syncTree, l.filter)
 
 	// Create text views for both panels
 	l.sourceView = tview.NewTextView().
@@ -49,10 +65,18 @@ func NewLayout(app *tview.Application, sourceRoot, targetRoot *compare.FileInfo,
 		SetScrollable(true)
 	l.targetView.SetBorder(true).SetTitle(" Target: " + targetDir + " ")
 
+	// Create detail line for the selected entry
+	l.detailBar = tview.NewTextView().
+		SetDynamicColors(true)
+
 	// Create status bar
 	l.statusBar = tview.NewTextView().
-		SetDynamicColors(true).
-		SetText("[yellow]↑↓[white] Navigate  [yellow]Space[white] Expand/Collapse  [yellow]d[white] Next Diff  [yellow]h/?[white] Help  [yellow]q[white] Quit   |   [green]✓[white] Same  [red]~[white] Modified  [blue]+[white] New  [gray]-[white] Deleted")
+		SetDynamicColors(true)
+
+	// Create input field for the search and go-to-path prompts, shown in
+	// place of the status bar
+	l.prompt = tview.NewInputField().
+		SetFieldBackgroundColor(tcell.ColorDarkBlue)
 
 	// Create title bar
 	titleBar := tview.NewTextView().
@@ -70,6 +94,7 @@ func NewLayout(app *tview.Application, sourceRoot, targetRoot *compare.FileInfo,
 	l.root = tview.NewFlex().SetDirection(tview.FlexRow).
 		AddItem(titleBar, 1, 0, false).
 		AddItem(content, 0, 1, false).
+		AddItem(l.detailBar, 1, 0, false).
 		AddItem(l.statusBar, 1, 0, false)
 
 	// Create help modal
@@ -99,26 +124,212 @@ func (l *Layout) render() {
 	for i, node := range l.flatNodes {
 		level := l.getLevel(node)
 		indent := strings.Repeat("  ", level)
-		
+
 		selected := i == l.currentIndex
-		prefix := "  "
+		prefix := " "
 		if selected {
-			prefix = "> "
+			prefix = ">"
+		}
+		if l.marked[node] {
+			prefix += "[yellow]*[-]"
+		} else {
+			prefix += " "
 		}
 
 		// Render source side
 		sourceText += l.renderNode(node, indent, prefix, true, selected) + "\n"
-		
+
 		// Render target side
 		targetText += l.renderNode(node, indent, prefix, false, selected) + "\n"
 	}
 
+	if len(l.flatNodes) == 0 && l.filter != FilterAll {
+		sourceText = "[gray]  No entries match the filter[-]"
+		targetText = sourceText
+	}
+
 	l.sourceView.SetText(sourceText)
 	l.targetView.SetText(targetText)
 
 	// Scroll to current selection
 	l.sourceView.ScrollTo(l.currentIndex, 0)
 	l.targetView.ScrollTo(l.currentIndex, 0)
+
+	l.detailBar.SetText(l.describeSelection())
+	l.statusBar.SetText(l.statusText())
+}
+
+// statusText returns the status bar text: key hints, legend, the active
+// filter and search, the marked entries and the number of entries that
+// could not be read
+func (l *Layout) statusText() string {
+	text := "[yellow]↑↓[white] Navigate  [yellow]Space[white] Expand/Collapse  [yellow]Enter[white] Diff  [yellow]d[white] Next Diff  [yellow]f/F[white] Filter  [yellow]/[white] Search  [yellow]h/?[white] Help  [yellow]q[white] Quit   |   [green]✓[white] Same  [red]~[white] Modified  [blue]+[white] New  [gray]-[white] Deleted  [fuchsia]≠[white] Type  [orange]◐[white] Has changes"
+	if l.filter != FilterAll {
+		text += fmt.Sprintf("   |   [aqua]Showing: %s[white]", l.filter)
+	}
+	if len(l.marked) > 0 {
+		text += "   |   [yellow]" + l.markSummary() + "[white]"
+	}
+	if l.query != "" {
+		text += fmt.Sprintf("   |   [aqua]/%s: ", tview.Escape(l.query))
+		if position := l.matchPosition(); position > 0 {
+			text += fmt.Sprintf("%d of %d[white]", position, len(l.matches))
+		} else {
+			text += fmt.Sprintf("%d matches[white]", len(l.matches))
+		}
+	}
+	if errors := countStatus(l.syncTree, compare.Error); errors > 0 {
+		text += fmt.Sprintf("   |   [yellow]! %d unreadable[white]", errors)
+	}
+	return text
+}
+
+// describeSelection returns the detail line for the selected entry
+func (l *Layout) describeSelection() string {
+	if l.currentIndex < 0 || l.currentIndex >= len(l.flatNodes) {
+		return ""
+	}
+	node := l.flatNodes[l.currentIndex]
+
+	details := []string{fmt.Sprintf("[::b]%s[::-]", tview.Escape(node.RelPath))}
+
+	for _, file := range []*compare.FileInfo{node.SourceFile, node.TargetFile} {
+		if file != nil && file.ErrorMessage != "" {
+			details = append(details, "[yellow]"+tview.Escape(file.ErrorMessage)+"[-]")
+		}
+	}
+
+	if node.Status == compare.Moved || node.Status == compare.Renamed {
+		details = append(details, moveDetail(node))
+	}
+
+	if node.Status == compare.TypeChanged && node.SourceFile != nil && node.TargetFile != nil {
+		details = append(details, fmt.Sprintf("[fuchsia]%s in source, %s in target[-]",
+			node.SourceFile.EntryType(), node.TargetFile.EntryType()))
+	}
+
+	if node.SourceFile != nil && node.SourceFile.RawDiffers {
+		details = append(details, "[green]same content once normalized; raw bytes differ[-]")
+	}
+
+	if node.Status == compare.Modified && node.SourceFile != nil && len(node.SourceFile.DiffPaths) > 0 {
+		details = append(details, pathsDetail(node.SourceFile.DiffPaths))
+	}
+
+	if node.Status == compare.MetaChanged && node.SourceFile != nil && node.TargetFile != nil {
+		details = append(details, metaDetail(node.SourceFile, node.TargetFile))
+	}
+
+	if link := symlinkDetail(node); link != "" {
+		details = append(details, link)
+	}
+
+	if l.mode == compare.BytesMode && node.Status == compare.Modified && !node.IsDir &&
+		node.SourceFile != nil && node.TargetFile != nil {
+		offset := node.SourceFile.DiffOffset
+		details = append(details, fmt.Sprintf("[red]differs at byte %d (0x%x)[-]", offset, offset))
+	}
+
+	return strings.Join(details, "  ")
+}
+
+// moveDetail describes where the selected moved or renamed file went to or
+// came from
+func moveDetail(node *SyncNode) string {
+	verb := "moved"
+	color := "aqua"
+	if node.Status == compare.Renamed {
+		verb = "renamed"
+		color = "teal"
+	}
+
+	var text string
+	if node.SourceFile != nil {
+		text = fmt.Sprintf("%s to %s", verb, node.SourceFile.Counterpart)
+		if node.Status == compare.Renamed {
+			text += fmt.Sprintf(" (%d%% similar)", node.SourceFile.Similarity)
+		}
+	} else if node.TargetFile != nil {
+		text = fmt.Sprintf("%s from %s", verb, node.TargetFile.Counterpart)
+		if node.Status == compare.Renamed {
+			text += fmt.Sprintf(" (%d%% similar)", node.TargetFile.Similarity)
+		}
+	}
+	return "[" + color + "]" + tview.Escape(text) + "[-]"
+}
+
+// maxDetailPaths caps the key paths listed on the detail line
+const maxDetailPaths = 5
+
+// pathsDetail lists the key paths at which structured data files differ,
+// e.g. "differs at: .spec.replicas, .items[2] (+3 more)"
+func pathsDetail(paths []string) string {
+	shown := paths
+	if len(shown) > maxDetailPaths {
+		shown = shown[:maxDetailPaths]
+	}
+	text := "differs at: " + strings.Join(shown, ", ")
+	if extra := len(paths) - len(shown); extra > 0 {
+		text += fmt.Sprintf(" (+%d more)", extra)
+	}
+	return "[red]" + tview.Escape(text) + "[-]"
+}
+
+// metaDetail describes the attributes that differ between both sides,
+// e.g. "mode: -rwxr-xr-x → -rw-r--r--"
+func metaDetail(source, target *compare.FileInfo) string {
+	var parts []string
+	for _, attr := range source.MetaDiff {
+		var from, to string
+		switch attr {
+		case compare.MetaMode:
+			from, to = source.Mode.String(), target.Mode.String()
+		case compare.MetaOwner:
+			from, to = fmt.Sprint(source.UID), fmt.Sprint(target.UID)
+		case compare.MetaGroup:
+			from, to = fmt.Sprint(source.GID), fmt.Sprint(target.GID)
+		case compare.MetaMtime:
+			from, to = source.ModTime.Format(time.DateTime), target.ModTime.Format(time.DateTime)
+		}
+		parts = append(parts, fmt.Sprintf("%s: %s → %s", attr, from, to))
+	}
+	return "[olive]" + tview.Escape(strings.Join(parts, ", ")) + "[-]"
+}
+
+// symlinkDetail describes where the selected entry's links point on each
+// side and whether they agree, or returns "" if neither side is a link
+func symlinkDetail(node *SyncNode) string {
+	source, target := node.SourceFile, node.TargetFile
+	if (source == nil || source.LinkTarget == "") && (target == nil || target.LinkTarget == "") {
+		return ""
+	}
+
+	// Followed links are compared by content, not by where they point
+	followed := ""
+	if (source != nil && source.LinkTarget != "" && !source.IsSymlink) ||
+		(target != nil && target.LinkTarget != "" && !target.IsSymlink) {
+		followed = ", followed"
+	}
+
+	if source != nil && target != nil && source.LinkTarget != "" && target.LinkTarget != "" {
+		if source.LinkTarget == target.LinkTarget {
+			return fmt.Sprintf("[aqua]symlink → %s on both sides, same target%s[-]", tview.Escape(source.LinkTarget), followed)
+		}
+		return fmt.Sprintf("[red]symlink targets differ: source → %s, target → %s%s[-]",
+			tview.Escape(source.LinkTarget), tview.Escape(target.LinkTarget), followed)
+	}
+
+	describe := func(file *compare.FileInfo) string {
+		switch {
+		case file == nil:
+			return "missing"
+		case file.LinkTarget == "":
+			return "not a link"
+		default:
+			return "→ " + tview.Escape(file.LinkTarget)
+		}
+	}
+	return fmt.Sprintf("[aqua]symlink: source %s, target %s%s[-]", describe(source), describe(target), followed)
 }
 
 // renderNode renders a single node for source or target side
@@ -138,13 +349,19 @@ func (l *Layout) renderNode(node *SyncNode, indent, prefix string, isSource, sel
 	if file == nil {
 		// Use different icon for non-existent files
 		if node.IsDir {
-			icon = "⊘"  // Empty set symbol for missing directory
+			icon = "⊘" // Empty set symbol for missing directory
 		} else {
-			icon = "∅"  // Empty set for missing file
+			icon = "∅" // Empty set for missing file
 		}
-		
+
 		text = "[Not exists]"
-		if isSource && node.Status == compare.New {
+		if node.Status == compare.Moved {
+			color = "gray"
+			statusIcon = " »"
+		} else if node.Status == compare.Renamed {
+			color = "gray"
+			statusIcon = " ↝"
+		} else if isSource && node.Status == compare.New {
 			color = "gray"
 			statusIcon = " +"
 		} else if !isSource && node.Status == compare.Deleted {
@@ -154,7 +371,7 @@ func (l *Layout) renderNode(node *SyncNode, indent, prefix string, isSource, sel
 			color = "gray"
 			statusIcon = ""
 		}
-		
+
 		if selected {
 			return fmt.Sprintf("%s%s[black:white]%s %s%s[-:-]", prefix, indent, icon, text, statusIcon)
 		}
@@ -162,7 +379,11 @@ func (l *Layout) renderNode(node *SyncNode, indent, prefix string, isSource, sel
 	}
 
 	// Determine icon for existing files
-	if node.IsDir {
+	name := node.Name
+	if file.LinkTarget != "" {
+		icon = "🔗"
+		name += " → " + file.LinkTarget
+	} else if file.IsDir {
 		if node.Expanded {
 			icon = "📂"
 		} else {
@@ -171,14 +392,25 @@ func (l *Layout) renderNode(node *SyncNode, indent, prefix string, isSource, sel
 	} else {
 		icon = "📄"
 	}
+	name = tview.Escape(name)
+	if l.matches[node] {
+		name = "[::u]" + name + "[::-]"
+	}
 
 	// Set status icon and color
 	switch node.Status {
 	case compare.Identical:
 		statusIcon = " ✓"
+		if file.RawDiffers {
+			statusIcon = " ≈"
+		}
 		color = "green"
 	case compare.Modified:
 		statusIcon = " ~"
+		if file.IsSymlink {
+			// The link points elsewhere; there is no content to differ
+			statusIcon = " ~→"
+		}
 		color = "red"
 	case compare.New:
 		statusIcon = " +"
@@ -186,14 +418,56 @@ func (l *Layout) renderNode(node *SyncNode, indent, prefix string, isSource, sel
 	case compare.Deleted:
 		statusIcon = " -"
 		color = "gray"
+	case compare.Error:
+		statusIcon = " !"
+		color = "yellow"
+	case compare.TypeChanged:
+		statusIcon = " ≠"
+		color = "fuchsia"
+	case compare.Moved:
+		statusIcon = " »"
+		color = "aqua"
+	case compare.Renamed:
+		statusIcon = " ↝"
+		color = "teal"
+	case compare.ContainsChanges:
+		statusIcon = " ◐"
+		color = "orange"
+	case compare.MetaChanged:
+		statusIcon = " ±"
+		color = "olive"
+	}
+
+	// Collapsed or not, a directory tells how much differs below it
+	if node.IsDir && node.Counts.Total() > 0 {
+		statusIcon += formatCounts(node.Counts)
 	}
 
 	// Highlight if selected
 	if selected {
-		return fmt.Sprintf("%s%s[black:white]%s %s%s[-:-]", prefix, indent, icon, node.Name, statusIcon)
+		return fmt.Sprintf("%s%s[black:white]%s %s%s[-:-]", prefix, indent, icon, name, statusIcon)
 	}
 
-	return fmt.Sprintf("%s%s[%s]%s %s%s[-]", prefix, indent, color, icon, node.Name, statusIcon)
+	return fmt.Sprintf("%s%s[%s]%s %s%s[-]", prefix, indent, color, icon, name, statusIcon)
+}
+
+// formatCounts renders the differing entry counts of a directory, e.g.
+// " (3~ 2+ 1-)"
+func formatCounts(counts DiffCounts) string {
+	var parts []string
+	if counts.Modified > 0 {
+		parts = append(parts, fmt.Sprintf("%d~", counts.Modified))
+	}
+	if counts.New > 0 {
+		parts = append(parts, fmt.Sprintf("%d+", counts.New))
+	}
+	if counts.Deleted > 0 {
+		parts = append(parts, fmt.Sprintf("%d-", counts.Deleted))
+	}
+	if counts.Other > 0 {
+		parts = append(parts, fmt.Sprintf("%d!", counts.Other))
+	}
+	return " (" + strings.Join(parts, " ") + ")"
 }
 
 // getLevel calculates the depth level of a node
@@ -232,16 +506,109 @@ func (l *Layout) ToggleExpand() {
 	node := l.flatNodes[l.currentIndex]
 	if node.IsDir {
 		node.Expanded = !node.Expanded
-		l.flatNodes = FlattenTree(l.syncTree)
+		l.flatNodes = FlattenTree(l.syncTree, l.filter)
 		l.render()
 	}
 }
 
+// OpenSelected expands or collapses the selected directory, or shows the
+// diff of the selected file: a line diff, or a hex dump for binary files
+func (l *Layout) OpenSelected() {
+	if l.currentIndex < 0 || l.currentIndex >= len(l.flatNodes) {
+		return
+	}
+
+	node := l.flatNodes[l.currentIndex]
+	if node.IsDir {
+		l.ToggleExpand()
+		return
+	}
+
+	source, target := l.diffPair(node)
+	if source == nil || target == nil {
+		return
+	}
+	// Line diffs of binary files are meaningless; compare their bytes
+	if isBinaryPair(source, target) {
+		view := NewHexView(l.app, source, target, l.closeOverlay)
+		l.showOverlay(view.GetRoot(), view.HandleKey)
+		return
+	}
+	view := NewDiffView(source, target, l.closeOverlay)
+	l.showOverlay(view.GetRoot(), view.HandleKey)
+}
+
+// isBinaryPair reports whether either file looks binary. Files that cannot
+// be read are left to the diff view to report.
+func isBinaryPair(source, target *compare.FileInfo) bool {
+	for _, file := range []*compare.FileInfo{source, target} {
+		if binary, err := compare.IsBinaryFile(file.Path); err == nil && binary {
+			return true
+		}
+	}
+	return false
+}
+
+// diffPair returns the source and target file to diff for node: both
+// sides of a file, or both ends of a moved or renamed one. Either is nil
+// if there is no pair of regular files to diff.
+func (l *Layout) diffPair(node *SyncNode) (source, target *compare.FileInfo) {
+	source, target = node.SourceFile, node.TargetFile
+	if node.Status == compare.Moved || node.Status == compare.Renamed {
+		if source != nil {
+			if counterpart := findNode(l.syncTree, source.Counterpart); counterpart != nil {
+				target = counterpart.TargetFile
+			}
+		} else if target != nil {
+			if counterpart := findNode(l.syncTree, target.Counterpart); counterpart != nil {
+				source = counterpart.SourceFile
+			}
+		}
+	}
+
+	for _, file := range []*compare.FileInfo{source, target} {
+		if file == nil || file.IsDir || file.IsSymlink || file.Status == compare.Error {
+			return nil, nil
+		}
+	}
+	return source, target
+}
+
+// showOverlay replaces the tree by a full-screen view. Key presses go to
+// input until closeOverlay is called.
+func (l *Layout) showOverlay(view tview.Primitive, input func(event *tcell.EventKey) *tcell.EventKey) {
+	l.overlay = input
+	l.app.SetRoot(view, true)
+}
+
+// closeOverlay returns to the tree, with the selection where it was
+func (l *Layout) closeOverlay() {
+	l.overlay = nil
+	l.app.SetRoot(l.root, true)
+	l.render()
+}
+
+// OverlayInput returns the key handler of the view shown instead of the
+// tree, or nil while the tree is shown
+func (l *Layout) OverlayInput() func(event *tcell.EventKey) *tcell.EventKey {
+	return l.overlay
+}
+
+// isDiffStop reports whether JumpToNextDiff stops at node. A directory
+// that only contains changes is skipped while expanded, since the changes
+// themselves are visible below it.
+func isDiffStop(node *SyncNode) bool {
+	if node.Status == compare.ContainsChanges {
+		return !node.Expanded
+	}
+	return node.Status != compare.Identical
+}
+
 // JumpToNextDiff jumps to the next file with differences
 func (l *Layout) JumpToNextDiff() {
 	start := l.currentIndex + 1
 	for i := start; i < len(l.flatNodes); i++ {
-		if l.flatNodes[i].Status != compare.Identical {
+		if isDiffStop(l.flatNodes[i]) {
 			l.currentIndex = i
 			l.render()
 			return
@@ -249,7 +616,7 @@ func (l *Layout) JumpToNextDiff() {
 	}
 	// Wrap around
 	for i := 0; i < start; i++ {
-		if l.flatNodes[i].Status != compare.Identical {
+		if isDiffStop(l.flatNodes[i]) {
 			l.currentIndex = i
 			l.render()
 			return
@@ -257,6 +624,103 @@ func (l *Layout) JumpToNextDiff() {
 	}
 }
 
+// JumpToCounterpart selects the other end of the selected move or rename
+func (l *Layout) JumpToCounterpart() {
+	if l.currentIndex < 0 || l.currentIndex >= len(l.flatNodes) {
+		return
+	}
+
+	node := l.flatNodes[l.currentIndex]
+	if node.Status != compare.Moved && node.Status != compare.Renamed {
+		return
+	}
+
+	var counterpart string
+	if node.SourceFile != nil {
+		counterpart = node.SourceFile.Counterpart
+	} else if node.TargetFile != nil {
+		counterpart = node.TargetFile.Counterpart
+	}
+
+	if target := findNode(l.syncTree, counterpart); target != nil {
+		l.reveal(target)
+	}
+}
+
+// reveal expands the ancestors of node and selects it, showing all
+// entries if the filter hides it
+func (l *Layout) reveal(node *SyncNode) {
+	for parent := node.Parent; parent != nil; parent = parent.Parent {
+		parent.Expanded = true
+	}
+	if !l.filter.Matches(node) {
+		l.filter = FilterAll
+	}
+	l.flatNodes = FlattenTree(l.syncTree, l.filter)
+
+	if i := l.indexOf(node); i >= 0 {
+		l.currentIndex = i
+	}
+	l.render()
+}
+
+// indexOf returns the position of node in the flattened tree, or -1 if it
+// is not shown
+func (l *Layout) indexOf(node *SyncNode) int {
+	for i, flat := range l.flatNodes {
+		if flat == node {
+			return i
+		}
+	}
+	return -1
+}
+
+// ToggleFilter switches between showing all entries and only those that
+// differ. Any status filter is turned off.
+func (l *Layout) ToggleFilter() {
+	if l.filter == FilterAll {
+		l.setFilter(FilterDifferences)
+	} else {
+		l.setFilter(FilterAll)
+	}
+}
+
+// CycleStatusFilter shows only modified, then only new, then only deleted
+// entries, then all entries again
+func (l *Layout) CycleStatusFilter() {
+	switch l.filter {
+	case FilterModified:
+		l.setFilter(FilterNew)
+	case FilterNew:
+		l.setFilter(FilterDeleted)
+	case FilterDeleted:
+		l.setFilter(FilterAll)
+	default:
+		l.setFilter(FilterModified)
+	}
+}
+
+// setFilter applies filter to the tree, keeping the selection on the
+// same entry or, if that is hidden now, its closest shown ancestor
+func (l *Layout) setFilter(filter Filter) {
+	var selected *SyncNode
+	if l.currentIndex >= 0 && l.currentIndex < len(l.flatNodes) {
+		selected = l.flatNodes[l.currentIndex]
+	}
+
+	l.filter = filter
+	l.flatNodes = FlattenTree(l.syncTree, l.filter)
+
+	l.currentIndex = 0
+	for node := selected; node != nil; node = node.Parent {
+		if i := l.indexOf(node); i >= 0 {
+			l.currentIndex = i
+			break
+		}
+	}
+	l.render()
+}
+
 // ShowHelp displays the help modal
 func (l *Layout) ShowHelp() {
 	l.app.SetRoot(l.helpModal, true)
@@ -271,8 +735,38 @@ Keyboard Shortcuts:
 Navigation:
   ↑/↓        Move selection up/down (both panels)
   Space      Expand/collapse folder
-  Enter      Expand/collapse folder
+  Enter      Expand/collapse folder, or show the
+             line diff of a file (n/p: next/prev
+             hunk, s: side-by-side, q/Esc: back);
+             binary files are shown as hex dumps
+             (n/p: next/prev differing region)
   d          Jump to next difference
+  o          Jump to the other end of a moved/renamed file
+
+Search:
+  /          Search names: text, glob (*.go,
+             src/*.c) or /regular expression/
+  n / N      Next/previous match
+  g          Go to a relative path (Tab completes)
+
+Marking:
+  m          Mark/unmark the selected entry
+  M          Mark/unmark all entries in the folder
+  *          Mark all entries with the selected status
+  u          Unmark all entries
+
+Changes (to the marked entries, or else the
+selected one):
+  >          Copy to the target
+  <          Copy to the source
+  x          Delete from one side
+             (moved to --trash-dir if given)
+  e          Export their paths to a file
+
+Filters:
+  f          Show only differences / show all
+  F          Show only modified, new, then deleted
+             entries, then all again
 
 Display:
   h / ?      Show this help
@@ -280,12 +774,23 @@ Display:
 
 Legend:
   ✓ (green)  Identical files
+  ≈ (green)  Same text, raw bytes differ (--ignore-*)
   ~ (red)    Modified files
   + (blue)   New files (target only)
   - (gray)   Deleted files (source only)
+  » (cyan)   Moved files (see detail line)
+  ↝ (teal)   Renamed and edited files (--find-renames)
+  ± (olive)  Same content, different attributes
+             (--compare-meta, see detail line)
+  ◐ (orange) Folder containing changes, with counts
+             of modified (~), new (+), deleted (-) and
+             other (!) entries below it
+  ≠ (purple) File in one folder, directory or link in the other
+  ! (yellow) Unreadable entries (see detail line)
+  🔗         Symbolic link, shown with where it points
+  ~→ (red)   Symbolic links pointing to different paths
   
 Note: Both panels are synchronized - navigation 
 affects both sides simultaneously.
 `
 }
-
@@ -0,0 +1,70 @@
+package tui
+
+import (
+	"path/filepath"
+	"testing"
+
+	"folder-diff-v2/internal/compare"
+
+	"github.com/rivo/tview"
+)
+
+// newTestLayout creates a layout over two sides given as entries, as if
+// compared with result
+func newTestLayout(sourceDir, targetDir string, source, target []entry, result *compare.ComparisonResult) *Layout {
+	return NewLayout(tview.NewApplication(), entryTree(sourceDir, source), entryTree(targetDir, target),
+		sourceDir, targetDir, result, "")
+}
+
+// selectedPath returns the slash-separated path of the selected entry
+func (l *Layout) selectedPath() string {
+	if l.currentIndex < 0 || l.currentIndex >= len(l.flatNodes) {
+		return ""
+	}
+	return filepath.ToSlash(l.flatNodes[l.currentIndex].RelPath)
+}
+
+func TestSetFilterKeepsSelection(t *testing.T) {
+	entries := []entry{
+		{"a/", compare.ContainsChanges},
+		{"a/changed.txt", compare.Modified},
+		{"a/same.txt", compare.Identical},
+		{"b.txt", compare.Identical},
+	}
+	l := newTestLayout("/source", "/target", entries, entries, &compare.ComparisonResult{Mode: compare.HashMode})
+
+	l.currentIndex = l.indexOf(findNode(l.syncTree, filepath.FromSlash("a/same.txt")))
+	l.ToggleFilter()
+	if l.filter != FilterDifferences {
+		t.Fatalf("filter %s after toggling, want %s", l.filter, FilterDifferences)
+	}
+	// The hidden selection falls back to its closest shown ancestor
+	if got := l.selectedPath(); got != "a" {
+		t.Errorf("selected %q, want %q", got, "a")
+	}
+
+	l.currentIndex = l.indexOf(findNode(l.syncTree, filepath.FromSlash("a/changed.txt")))
+	l.ToggleFilter()
+	if got := l.selectedPath(); l.filter != FilterAll || got != "a/changed.txt" {
+		t.Errorf("filter %s, selected %q; want %s, %q", l.filter, got, FilterAll, "a/changed.txt")
+	}
+
+	var cycle []Filter
+	for i := 0; i < 4; i++ {
+		l.CycleStatusFilter()
+		cycle = append(cycle, l.filter)
+	}
+	want := []Filter{FilterModified, FilterNew, FilterDeleted, FilterAll}
+	for i := range want {
+		if cycle[i] != want[i] {
+			t.Errorf("status filters cycle through %v, want %v", cycle, want)
+			break
+		}
+	}
+
+	// Nothing is new: the tree is empty and nothing is selected
+	l.setFilter(FilterNew)
+	if len(l.flatNodes) != 0 || l.selectedPath() != "" {
+		t.Errorf("new filter shows %d entries", len(l.flatNodes))
+	}
+}
@@ -0,0 +1,189 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// ToggleMark marks or unmarks the selected entry and moves on to the next
+func (l *Layout) ToggleMark() {
+	node := l.selected()
+	if node == nil {
+		return
+	}
+	l.setMarked(node, !l.marked[node])
+	if l.currentIndex < len(l.flatNodes)-1 {
+		l.currentIndex++
+	}
+	l.render()
+}
+
+// MarkDirectory marks the shown entries of the selected directory, or of
+// the one containing the selected file. If they are all marked already,
+// they are unmarked instead.
+func (l *Layout) MarkDirectory() {
+	node := l.selected()
+	if node == nil {
+		return
+	}
+	dir := node
+	if !node.IsDir || !node.Expanded {
+		dir = node.Parent
+	}
+
+	var children []*SyncNode
+	allMarked := true
+	for _, child := range dir.Children {
+		if l.filter.Matches(child) {
+			children = append(children, child)
+			allMarked = allMarked && l.marked[child]
+		}
+	}
+	for _, child := range children {
+		l.setMarked(child, !allMarked)
+	}
+	l.render()
+}
+
+// MarkStatus marks every entry in the tree with the status of the selected
+// one, e.g. all new files
+func (l *Layout) MarkStatus() {
+	node := l.selected()
+	if node == nil {
+		return
+	}
+	for _, n := range allNodes(l.syncTree) {
+		if n.Status == node.Status && l.filter.Matches(n) {
+			l.setMarked(n, true)
+		}
+	}
+	l.render()
+}
+
+// ClearMarks unmarks all entries
+func (l *Layout) ClearMarks() {
+	l.marked = nil
+	l.render()
+}
+
+// setMarked marks or unmarks node
+func (l *Layout) setMarked(node *SyncNode, marked bool) {
+	if !marked {
+		delete(l.marked, node)
+		return
+	}
+	if l.marked == nil {
+		l.marked = make(map[*SyncNode]bool)
+	}
+	l.marked[node] = true
+}
+
+// actionNodes returns the entries copy, delete and export apply to: the
+// marked ones in display order, leaving out those below another marked
+// entry, or else the selected one
+func (l *Layout) actionNodes() []*SyncNode {
+	if len(l.marked) == 0 {
+		if node := l.selected(); node != nil {
+			return []*SyncNode{node}
+		}
+		return nil
+	}
+
+	var nodes []*SyncNode
+	var walk func(node *SyncNode)
+	walk = func(node *SyncNode) {
+		for _, child := range node.Children {
+			if l.marked[child] {
+				nodes = append(nodes, child)
+				continue
+			}
+			walk(child)
+		}
+	}
+	walk(l.syncTree)
+	return nodes
+}
+
+// describeNodes names the entries of an action in dialogs
+func describeNodes(nodes []*SyncNode) string {
+	if len(nodes) == 1 {
+		return nodes[0].RelPath
+	}
+	return plural(len(nodes), "marked entry")
+}
+
+// markSummary describes the marked entries for the status bar, e.g.
+// "3 marked: 12 files, 1.5 MiB", counting the files below marked
+// directories on either side once
+func (l *Layout) markSummary() string {
+	files := 0
+	var size int64
+	for _, root := range l.actionNodes() {
+		for _, n := range subtree(root) {
+			file := n.SourceFile
+			if file == nil {
+				file = n.TargetFile
+			}
+			if !file.IsDir {
+				files++
+				size += file.Size
+			}
+		}
+	}
+	return fmt.Sprintf("%d marked: %s, %s", len(l.marked), plural(files, "file"), formatSize(size))
+}
+
+// subtree returns node and the nodes below it in display order
+func subtree(node *SyncNode) []*SyncNode {
+	return append([]*SyncNode{node}, allNodes(node)...)
+}
+
+// ExportSelected asks for a file and writes the relative paths of the
+// marked entries, or else the selected one, and of everything below them
+// to it, one per line, as accepted by rsync --files-from
+func (l *Layout) ExportSelected() {
+	roots := l.actionNodes()
+	if len(roots) == 0 {
+		return
+	}
+
+	var paths []string
+	files := 0
+	var size int64
+	for _, root := range roots {
+		for _, n := range subtree(root) {
+			paths = append(paths, filepath.ToSlash(n.RelPath))
+			file := n.SourceFile
+			if file == nil {
+				file = n.TargetFile
+			}
+			if !file.IsDir {
+				files++
+				size += file.Size
+			}
+		}
+	}
+
+	l.showPrompt("Export to: ", "", nil, nil, func(key tcell.Key) {
+		path := l.prompt.GetText()
+		l.closePrompt()
+		if key != tcell.KeyEnter || path == "" {
+			return
+		}
+
+		question := fmt.Sprintf("Export the paths of %s to %s?\n\n%s, %s, %s",
+			describeNodes(roots), path, plural(len(paths), "path"), plural(files, "file"), formatSize(size))
+		l.confirm(question, "Export", func() {
+			if err := os.WriteFile(path, []byte(strings.Join(paths, "\n")+"\n"), 0o644); err != nil {
+				l.notify("[yellow]" + tview.Escape(err.Error()) + "[-]")
+				return
+			}
+			l.notify(fmt.Sprintf("[green]Exported %s to %s[-]", plural(len(paths), "path"), tview.Escape(path)))
+		})
+	})
+}
@@ -0,0 +1,264 @@
+package tui
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+// maxCompletions caps the entries offered by the go-to-path prompt
+const maxCompletions = 20
+
+// compileQuery turns a search query into a matcher of entries:
+//   - /expr/ matches the relative path against a regular expression
+//   - a query with *, ? or [ is a glob, matched against the relative path if
+//     it contains a slash and against the name otherwise
+//   - anything else matches as a substring of the relative path, ignoring
+//     case unless the query has upper-case letters
+func compileQuery(query string) (func(node *SyncNode) bool, error) {
+	if len(query) >= 2 && strings.HasPrefix(query, "/") && strings.HasSuffix(query, "/") {
+		re, err := regexp.Compile(query[1 : len(query)-1])
+		if err != nil {
+			return nil, err
+		}
+		return func(node *SyncNode) bool {
+			return re.MatchString(filepath.ToSlash(node.RelPath))
+		}, nil
+	}
+
+	if strings.ContainsAny(query, "*?[") {
+		if _, err := path.Match(query, ""); err != nil {
+			return nil, err
+		}
+		return func(node *SyncNode) bool {
+			if strings.Contains(query, "/") {
+				matched, _ := path.Match(query, filepath.ToSlash(node.RelPath))
+				return matched
+			}
+			matched, _ := path.Match(query, node.Name)
+			return matched
+		}, nil
+	}
+
+	foldCase := strings.ToLower(query) == query
+	return func(node *SyncNode) bool {
+		relPath := filepath.ToSlash(node.RelPath)
+		if foldCase {
+			relPath = strings.ToLower(relPath)
+		}
+		return strings.Contains(relPath, query)
+	}, nil
+}
+
+// allNodes returns the nodes below root in display order, whether their
+// directories are expanded or not
+func allNodes(root *SyncNode) []*SyncNode {
+	var nodes []*SyncNode
+	var walk func(node *SyncNode)
+	walk = func(node *SyncNode) {
+		for _, child := range node.Children {
+			nodes = append(nodes, child)
+			walk(child)
+		}
+	}
+	walk(root)
+	return nodes
+}
+
+// setQuery searches the whole tree for query. An empty query ends the
+// search.
+func (l *Layout) setQuery(query string) error {
+	l.query = query
+	l.matches = nil
+	if query == "" {
+		return nil
+	}
+
+	match, err := compileQuery(query)
+	if err != nil {
+		return err
+	}
+	l.matches = make(map[*SyncNode]bool)
+	for _, node := range allNodes(l.syncTree) {
+		if match(node) {
+			l.matches[node] = true
+		}
+	}
+	return nil
+}
+
+// selectMatch reveals the first match after from in display order, or
+// before it if forward is false, wrapping around. from itself is
+// considered last. It reports whether there was a match.
+func (l *Layout) selectMatch(from *SyncNode, forward bool) bool {
+	nodes := allNodes(l.syncTree)
+	start := 0
+	for i, node := range nodes {
+		if node == from {
+			start = i
+			break
+		}
+	}
+
+	for step := 1; step <= len(nodes); step++ {
+		i := start + step
+		if !forward {
+			i = start - step
+		}
+		node := nodes[(i%len(nodes)+len(nodes))%len(nodes)]
+		if l.matches[node] {
+			l.reveal(node)
+			return true
+		}
+	}
+	return false
+}
+
+// selected returns the selected node, or nil
+func (l *Layout) selected() *SyncNode {
+	if l.currentIndex < 0 || l.currentIndex >= len(l.flatNodes) {
+		return nil
+	}
+	return l.flatNodes[l.currentIndex]
+}
+
+// StartSearch opens the search prompt. Each keystroke selects the first
+// match from the selection on, expanding its folders; Enter keeps the
+// search for NextMatch and PreviousMatch, Esc ends it and returns to where
+// the search started.
+func (l *Layout) StartSearch() {
+	origin := l.selected()
+
+	l.showPrompt("/", l.query, nil, func(text string) {
+		if err := l.setQuery(text); err != nil {
+			l.detailBar.SetText("[yellow]" + tview.Escape(err.Error()) + "[-]")
+			return
+		}
+		switch {
+		case origin != nil && l.matches[origin]:
+			l.reveal(origin)
+		case !l.selectMatch(origin, true):
+			l.render()
+		}
+	}, func(key tcell.Key) {
+		if key == tcell.KeyEscape {
+			l.setQuery("")
+			if origin != nil {
+				l.reveal(origin)
+			}
+		}
+		l.closePrompt()
+	})
+}
+
+// NextMatch selects the next entry matching the search
+func (l *Layout) NextMatch() {
+	l.selectMatch(l.selected(), true)
+}
+
+// PreviousMatch selects the previous entry matching the search
+func (l *Layout) PreviousMatch() {
+	l.selectMatch(l.selected(), false)
+}
+
+// matchPosition returns the 1-based position of the selection among the
+// matches, or 0 if it is not one of them
+func (l *Layout) matchPosition() int {
+	position := 0
+	selected := l.selected()
+	for _, node := range allNodes(l.syncTree) {
+		if l.matches[node] {
+			position++
+			if node == selected {
+				return position
+			}
+		}
+	}
+	return 0
+}
+
+// GoToPath opens a prompt for a relative path and selects the entry at it,
+// completing path segments from the tree
+func (l *Layout) GoToPath() {
+	l.showPrompt("Go to: ", "", l.completePath, nil, func(key tcell.Key) {
+		text := l.prompt.GetText()
+		l.closePrompt()
+		if key != tcell.KeyEnter || text == "" {
+			return
+		}
+
+		relPath := filepath.Clean(filepath.FromSlash(strings.TrimPrefix(text, "/")))
+		if node := findNode(l.syncTree, relPath); node != nil && node != l.syncTree {
+			l.reveal(node)
+			return
+		}
+		l.detailBar.SetText(fmt.Sprintf("[yellow]No such entry: %s[-]", tview.Escape(text)))
+	})
+}
+
+// completePath returns the entries of the directory typed so far whose
+// paths start with text
+func (l *Layout) completePath(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	relPath := filepath.FromSlash(text)
+	dir := filepath.Dir(relPath)
+	if strings.HasSuffix(text, "/") {
+		dir = filepath.Clean(relPath)
+	}
+	parent := findNode(l.syncTree, dir)
+	if parent == nil {
+		return nil
+	}
+
+	var entries []string
+	for _, child := range parent.Children {
+		if !strings.HasPrefix(child.RelPath, relPath) {
+			continue
+		}
+		entry := filepath.ToSlash(child.RelPath)
+		if child.IsDir {
+			entry += "/"
+		}
+		entries = append(entries, entry)
+		if len(entries) == maxCompletions {
+			break
+		}
+	}
+	return entries
+}
+
+// showPrompt replaces the status bar by an input field. changed is called
+// as the text changes and done when Enter or Esc is pressed; complete, if
+// not nil, offers completions.
+func (l *Layout) showPrompt(label, text string, complete func(string) []string, changed func(string), done func(tcell.Key)) {
+	l.prompt.SetChangedFunc(nil).
+		SetText(text).
+		SetLabel(label).
+		SetAutocompleteFunc(complete).
+		SetChangedFunc(changed).
+		SetDoneFunc(done)
+
+	l.root.RemoveItem(l.statusBar)
+	l.root.AddItem(l.prompt, 1, 0, true)
+	l.overlay = func(event *tcell.EventKey) *tcell.EventKey {
+		return event
+	}
+	l.app.SetFocus(l.prompt)
+}
+
+// closePrompt puts the status bar back in place of the input field
+func (l *Layout) closePrompt() {
+	l.root.RemoveItem(l.prompt)
+	l.root.AddItem(l.statusBar, 1, 0, false)
+	l.overlay = nil
+	l.app.SetFocus(l.root)
+	l.render()
+}
@@ -19,6 +19,35 @@ type SyncNode struct {
 	Children     []*SyncNode
 	Parent       *SyncNode
 	Expanded     bool
+	Counts       DiffCounts // Differing entries below a directory
+}
+
+// DiffCounts counts the differing entries below a directory
+type DiffCounts struct {
+	Modified int
+	New      int
+	Deleted  int
+	Other    int // Moved, renamed, type and metadata changes, unreadable entries
+}
+
+// Total returns the number of differing entries
+func (c DiffCounts) Total() int {
+	return c.Modified + c.New + c.Deleted + c.Other
+}
+
+// add counts an entry with the given status
+func (c *DiffCounts) add(status compare.FileStatus) {
+	switch status {
+	case compare.Identical, compare.ContainsChanges:
+	case compare.Modified:
+		c.Modified++
+	case compare.New:
+		c.New++
+	case compare.Deleted:
+		c.Deleted++
+	default:
+		c.Other++
+	}
 }
 
 // BuildSyncTree creates a synchronized tree from source and target files
@@ -51,9 +80,42 @@ func BuildSyncTree(sourceRoot, targetRoot *compare.FileInfo) *SyncNode {
 	// Build parent-child relationships
 	buildHierarchy(pathMap, root)
 
+	// Roll directory statuses up from their descendants
+	aggregateStatus(root)
+
 	return root
 }
 
+// aggregateStatus recomputes the Counts of node and its descendant
+// directories. A directory present on both sides becomes ContainsChanges
+// when anything below it differs, and Identical again when nothing does.
+// Only files are counted, plus directories that are themselves a type
+// conflict, have changed metadata or are unreadable.
+func aggregateStatus(node *SyncNode) {
+	node.Counts = DiffCounts{}
+	for _, child := range node.Children {
+		if child.IsDir {
+			aggregateStatus(child)
+			node.Counts.Modified += child.Counts.Modified
+			node.Counts.New += child.Counts.New
+			node.Counts.Deleted += child.Counts.Deleted
+			node.Counts.Other += child.Counts.Other
+		}
+		if !child.IsDir || child.Status == compare.TypeChanged || child.Status == compare.MetaChanged ||
+			child.Status == compare.Error {
+			node.Counts.add(child.Status)
+		}
+	}
+
+	if node.Status == compare.Identical || node.Status == compare.ContainsChanges {
+		if node.Counts.Total() > 0 {
+			node.Status = compare.ContainsChanges
+		} else {
+			node.Status = compare.Identical
+		}
+	}
+}
+
 // collectPaths collects all paths from a tree
 func collectPaths(node *compare.FileInfo, pathMap map[string]*SyncNode, isSource bool, root *SyncNode) {
 	if node == nil {
@@ -85,7 +147,9 @@ func collectPaths(node *compare.FileInfo, pathMap map[string]*SyncNode, isSource
 			syncNode.TargetFile = child
 		}
 
-		// Update status based on both sides
+		// Update status based on both sides. A path that is a directory
+		// on either side stays expandable, so that under a type conflict
+		// the directory's subtree is still shown.
 		syncNode.Status = determineStatus(syncNode.SourceFile, syncNode.TargetFile)
 		syncNode.IsDir = (syncNode.SourceFile != nil && syncNode.SourceFile.IsDir) ||
 			(syncNode.TargetFile != nil && syncNode.TargetFile.IsDir)
@@ -148,22 +212,62 @@ func sortChildren(node *SyncNode) {
 
 // determineStatus determines the status based on source and target files
 func determineStatus(source, target *compare.FileInfo) compare.FileStatus {
+	if (source != nil && source.Status == compare.Error) || (target != nil && target.Status == compare.Error) {
+		return compare.Error
+	}
 	if source == nil && target != nil {
+		if target.Status == compare.Moved || target.Status == compare.Renamed {
+			return target.Status
+		}
 		return compare.New
 	}
 	if source != nil && target == nil {
+		if source.Status == compare.Moved || source.Status == compare.Renamed {
+			return source.Status
+		}
 		return compare.Deleted
 	}
 	if source != nil && target != nil {
 		// Both exist - check their status
+		if source.Status == compare.TypeChanged || target.Status == compare.TypeChanged {
+			return compare.TypeChanged
+		}
 		if source.Status == compare.Modified || target.Status == compare.Modified {
 			return compare.Modified
 		}
+		if source.Status == compare.MetaChanged || target.Status == compare.MetaChanged {
+			return compare.MetaChanged
+		}
 		return compare.Identical
 	}
 	return compare.Identical
 }
 
+// countStatus counts the nodes below root with the given status
+func countStatus(root *SyncNode, status compare.FileStatus) int {
+	count := 0
+	for _, child := range root.Children {
+		if child.Status == status {
+			count++
+		}
+		count += countStatus(child, status)
+	}
+	return count
+}
+
+// findNode returns the node at relPath below root, or nil
+func findNode(root *SyncNode, relPath string) *SyncNode {
+	if root.RelPath == relPath {
+		return root
+	}
+	for _, child := range root.Children {
+		if child.RelPath == relPath || strings.HasPrefix(relPath, child.RelPath+string(filepath.Separator)) {
+			return findNode(child, relPath)
+		}
+	}
+	return nil
+}
+
 // contains checks if a node is in the children slice
 func contains(children []*SyncNode, node *SyncNode) bool {
 	for _, child := range children {
@@ -174,28 +278,76 @@ func contains(children []*SyncNode, node *SyncNode) bool {
 	return false
 }
 
-// FlattenTree converts tree to flat list for display
-func FlattenTree(root *SyncNode) []*SyncNode {
+// Filter selects the entries shown in the tree
+type Filter int
+
+const (
+	FilterAll         Filter = iota // Every entry
+	FilterDifferences               // Entries that differ in any way
+	FilterModified                  // Modified files
+	FilterNew                       // Entries only in the target
+	FilterDeleted                   // Entries only in the source
+)
+
+// String returns the filter's name as shown in the status bar
+func (f Filter) String() string {
+	switch f {
+	case FilterDifferences:
+		return "differences"
+	case FilterModified:
+		return "modified"
+	case FilterNew:
+		return "new"
+	case FilterDeleted:
+		return "deleted"
+	default:
+		return "all"
+	}
+}
+
+// Matches reports whether node is shown under the filter. A directory is
+// shown when it matches itself or anything below it does.
+func (f Filter) Matches(node *SyncNode) bool {
+	switch f {
+	case FilterDifferences:
+		return node.Status != compare.Identical
+	case FilterModified:
+		return node.Status == compare.Modified || node.Counts.Modified > 0
+	case FilterNew:
+		return node.Status == compare.New || node.Counts.New > 0
+	case FilterDeleted:
+		return node.Status == compare.Deleted || node.Counts.Deleted > 0
+	default:
+		return true
+	}
+}
+
+// FlattenTree converts tree to flat list for display, leaving out the
+// entries the filter does not match
+func FlattenTree(root *SyncNode, filter Filter) []*SyncNode {
 	var result []*SyncNode
-	flattenRecursive(root, &result, 0)
+	flattenRecursive(root, &result, 0, filter)
 	return result
 }
 
 // flattenRecursive recursively flattens the tree
-func flattenRecursive(node *SyncNode, result *[]*SyncNode, level int) {
+func flattenRecursive(node *SyncNode, result *[]*SyncNode, level int, filter Filter) {
 	if node == nil {
 		return
 	}
 
 	// Skip root "."
 	if node.RelPath != "." {
+		if !filter.Matches(node) {
+			return
+		}
 		*result = append(*result, node)
 	}
 
 	// Only process children if expanded
 	if node.Expanded {
 		for _, child := range node.Children {
-			flattenRecursive(child, result, level+1)
+			flattenRecursive(child, result, level+1, filter)
 		}
 	}
 }
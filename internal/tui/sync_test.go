@@ -0,0 +1,158 @@
+package tui
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"folder-diff-v2/internal/compare"
+)
+
+// entry describes a scanned entry for buildTrees: a relative path, with a
+// trailing "/" for directories, and its status on one side
+type entry struct {
+	path   string
+	status compare.FileStatus
+}
+
+// entryTree builds the tree of one side rooted at root
+func entryTree(root string, entries []entry) *compare.FileInfo {
+	var infos []*compare.FileInfo
+	for _, e := range entries {
+		relPath := filepath.FromSlash(strings.TrimSuffix(e.path, "/"))
+		infos = append(infos, &compare.FileInfo{
+			Path:    filepath.Join(root, relPath),
+			RelPath: relPath,
+			IsDir:   strings.HasSuffix(e.path, "/"),
+			Status:  e.status,
+		})
+	}
+	return BuildTree(infos, root)
+}
+
+// buildTrees builds the synchronized tree of two sides given as entries
+func buildTrees(source, target []entry) *SyncNode {
+	return BuildSyncTree(entryTree("/source", source), entryTree("/target", target))
+}
+
+func TestSyncTreeMetaChanged(t *testing.T) {
+	source := []entry{
+		{"bin/", compare.ContainsChanges},
+		{"bin/tool", compare.MetaChanged},
+		{"bin/other", compare.Identical},
+		{"conf/", compare.MetaChanged},
+		{"readme", compare.Modified},
+	}
+	target := source
+
+	root := buildTrees(source, target)
+
+	tests := []struct {
+		path   string
+		status compare.FileStatus
+		counts DiffCounts
+	}{
+		{".", compare.ContainsChanges, DiffCounts{Modified: 1, Other: 2}},
+		{"bin", compare.ContainsChanges, DiffCounts{Other: 1}},
+		{"bin/tool", compare.MetaChanged, DiffCounts{}},
+		{"bin/other", compare.Identical, DiffCounts{}},
+		{"conf", compare.MetaChanged, DiffCounts{}},
+		{"readme", compare.Modified, DiffCounts{}},
+	}
+	for _, tt := range tests {
+		node := findNode(root, filepath.FromSlash(tt.path))
+		if node == nil {
+			t.Errorf("%s: not in the tree", tt.path)
+			continue
+		}
+		if node.Status != tt.status || node.Counts != tt.counts {
+			t.Errorf("%s: status %s, counts %+v; want %s, %+v", tt.path, node.Status, node.Counts, tt.status, tt.counts)
+		}
+	}
+}
+
+func TestDetermineStatus(t *testing.T) {
+	file := func(status compare.FileStatus) *compare.FileInfo {
+		return &compare.FileInfo{Status: status}
+	}
+	tests := []struct {
+		name           string
+		source, target *compare.FileInfo
+		want           compare.FileStatus
+	}{
+		{"identical", file(compare.Identical), file(compare.Identical), compare.Identical},
+		{"meta changed", file(compare.MetaChanged), file(compare.MetaChanged), compare.MetaChanged},
+		{"meta changed after a copy on one side", file(compare.Identical), file(compare.MetaChanged), compare.MetaChanged},
+		{"content wins over metadata", file(compare.MetaChanged), file(compare.Modified), compare.Modified},
+		{"unreadable wins", file(compare.MetaChanged), file(compare.Error), compare.Error},
+		{"only in source", file(compare.Deleted), nil, compare.Deleted},
+		{"only in target", nil, file(compare.New), compare.New},
+	}
+	for _, tt := range tests {
+		if got := determineStatus(tt.source, tt.target); got != tt.want {
+			t.Errorf("%s: determineStatus = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFlattenTreeFilter(t *testing.T) {
+	source := []entry{
+		{"docs/", compare.ContainsChanges},
+		{"docs/guide.md", compare.Modified},
+		{"docs/index.md", compare.Identical},
+		{"old/", compare.Deleted},
+		{"old/a.txt", compare.Deleted},
+		{"src/", compare.ContainsChanges},
+		{"src/gone.go", compare.Deleted},
+		{"src/main.go", compare.Identical},
+		{"same/", compare.Identical},
+		{"same/x", compare.Identical},
+		{"mode.sh", compare.MetaChanged},
+		{"readme", compare.Identical},
+	}
+	target := []entry{
+		{"docs/", compare.ContainsChanges},
+		{"docs/guide.md", compare.Modified},
+		{"docs/index.md", compare.Identical},
+		{"src/", compare.ContainsChanges},
+		{"src/main.go", compare.Identical},
+		{"src/new.go", compare.New},
+		{"same/", compare.Identical},
+		{"same/x", compare.Identical},
+		{"mode.sh", compare.MetaChanged},
+		{"readme", compare.Identical},
+	}
+	root := buildTrees(source, target)
+
+	tests := []struct {
+		filter Filter
+		want   string
+	}{
+		{FilterAll, "docs docs/guide.md docs/index.md old old/a.txt same same/x src src/gone.go src/main.go src/new.go mode.sh readme"},
+		{FilterDifferences, "docs docs/guide.md old old/a.txt src src/gone.go src/new.go mode.sh"},
+		{FilterModified, "docs docs/guide.md"},
+		{FilterNew, "src src/new.go"},
+		{FilterDeleted, "old old/a.txt src src/gone.go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter.String(), func(t *testing.T) {
+			var paths []string
+			for _, node := range FlattenTree(root, tt.filter) {
+				paths = append(paths, filepath.ToSlash(node.RelPath))
+			}
+			if got := strings.Join(paths, " "); got != tt.want {
+				t.Errorf("FlattenTree = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Collapsed directories hide their entries under every filter
+	findNode(root, "src").Expanded = false
+	var paths []string
+	for _, node := range FlattenTree(root, FilterNew) {
+		paths = append(paths, filepath.ToSlash(node.RelPath))
+	}
+	if got := strings.Join(paths, " "); got != "src" {
+		t.Errorf("FlattenTree with src collapsed = %q, want %q", got, "src")
+	}
+}